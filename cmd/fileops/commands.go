@@ -0,0 +1,926 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"fileops/internal/cfg"
+	"fileops/internal/infra"
+	"fileops/internal/ops"
+	"fileops/internal/proc"
+	"fileops/internal/render"
+	"fileops/internal/secure"
+	"fileops/internal/watch"
+	"fileops/internal/wiki"
+)
+
+// Codes de sortie des sous-commandes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage signale une erreur de syntaxe (code de sortie 2).
+type errUsage string
+
+func (e errUsage) Error() string { return string(e) }
+
+const usage = `Usage : fileops [--config FICHIER] [--set CLE=VALEUR] [COMMANDE] [ARGS]
+
+Sans commande, le menu interactif est lancé.
+
+Commandes :
+  analyze FICHIER [--filter EXPR] [--case] [--word] [--head N] [--tail N] [--vocab]
+  batch [REPERTOIRE] [--ext .txt,.md] [--include MOTIF] [--exclude MOTIF]
+        [--min-size 10K] [--max-size 5M] [--since DATE|DUREE] [--before DATE|DUREE]
+        [--max-depth N] [--symlinks ignore|files|all] [--ignore-file NOM] [--full] [--vocab]
+  wiki [LANGUE:]TITRE[,...] [--lang LANGUE] [--base-url URL]
+       [--offline] [--no-cache] [--max-age DUREE] [--save-as txt|md|json|html]
+  crawl [LANGUE:]TITRE[,...] [--depth N] [--max-pages N] [--ns 0,14]
+        [--save-as txt|md|json|html]
+  crawl --resume [--depth N] [--max-pages N]
+  proc list [--filter KW] [--top N]
+  proc tree [PID]
+  proc kill PID [--tree] [--force] [--yes]
+  secure lock FICHIER [-- COMMANDE ARGS...]
+  secure unlock|status|ro FICHIER
+  secure break FICHIER --yes
+  secure verify
+  containers ls
+  containers stats [ID...] [--sort cpu|mem] [--samples N] [--interval DUREE]
+  containers inspect ID
+  containers start|stop|restart|pause|unpause|rm ID [--timeout DUREE] [--force] --yes
+  disk [CHEMIN...]               (sans chemin : tous les montages)
+  watch [--interval DUREE] [--rule REGLE]... [--sink DEST]... [--repeat DUREE] [--once]
+  config show
+  help
+
+Options globales :
+  --config FICHIER               fichier JSON ou clé=valeur (couche projet)
+  --set CLE=VALEUR               surcharge une clé, répétable
+  --format text|json|jsonl|csv   format de sortie (défaut : output_format)
+
+Configuration, de la moins à la plus prioritaire : valeurs par défaut,
+/etc/fileops, dossier utilisateur (~/.config/fileops), dossier courant
+(config.json, config.txt) ou --config, variables FILEOPS_<CLE>, options.
+`
+
+// runCommand exécute une sous-commande et renvoie le code de sortie.
+func runCommand(conf cfg.Config, src cfg.Sources, args []string) int {
+	var err error
+	switch args[0] {
+	case "analyze":
+		err = cmdAnalyze(conf, args[1:])
+	case "batch":
+		err = cmdBatch(conf, args[1:])
+	case "wiki":
+		err = cmdWiki(conf, args[1:])
+	case "crawl":
+		err = cmdCrawl(conf, args[1:])
+	case "proc":
+		err = cmdProc(conf, args[1:])
+	case "secure":
+		err = cmdSecure(conf, args[1:])
+	case "containers":
+		err = cmdContainers(conf, args[1:])
+	case "disk":
+		err = cmdDisk(conf, args[1:])
+	case "watch":
+		err = cmdWatch(conf, args[1:])
+	case "config":
+		err = cmdConfig(conf, src, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return exitOK
+	default:
+		err = errUsage(fmt.Sprintf("commande inconnue : %s", args[0]))
+	}
+
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintln(os.Stderr, "Erreur :", err)
+	var u errUsage
+	if errors.As(err, &u) {
+		fmt.Fprintln(os.Stderr, "Voir « fileops help ».")
+		return exitUsage
+	}
+	return exitError
+}
+
+// newFlagSet crée un FlagSet qui renvoie ses erreurs au lieu de quitter.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseArgs autorise les options après les arguments positionnels
+// (ex. « analyze FICHIER --head 5 ») et renvoie ces arguments ; tout ce
+// qui suit « -- » est positionnel.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, a := range args {
+		if a == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	pos, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	return append(pos, rest...), nil
+}
+
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage(err.Error())
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+func cmdAnalyze(conf cfg.Config, args []string) error {
+	fs := newFlagSet("analyze")
+	filter := fs.String("filter", "", "expression de filtre pour filtered.txt / filtered_not.txt")
+	caseSens := fs.Bool("case", false, "filtre sensible à la casse")
+	word := fs.Bool("word", false, "filtre sur mots entiers")
+	head := fs.Int("head", 0, "nombre de lignes pour head.txt")
+	tail := fs.Int("tail", -1, "nombre de lignes pour tail.txt (défaut : --head)")
+	vocab := fs.Bool("vocab", false, "fréquences de mots et n-grammes (vocab_<fichier>.txt)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	path := conf.DefaultFile
+	switch len(pos) {
+	case 0:
+	case 1:
+		path = pos[0]
+	default:
+		return errUsage("analyze attend un seul fichier")
+	}
+	if *tail < 0 {
+		*tail = *head
+	}
+
+	var opt ops.AnalyzeOptions
+	if *filter != "" {
+		f, err := ops.ParseFilter(*filter, ops.FilterOptions{CaseSensitive: *caseSens, WholeWord: *word})
+		if err != nil {
+			return errUsage(err.Error())
+		}
+		opt.Filter = f
+		withFilter(conf, &opt)
+	}
+	if *head > 0 || *tail > 0 {
+		withHeadTail(conf, &opt, *head, *tail)
+	}
+	if *vocab {
+		opt.VocabTopN = conf.VocabTopN
+	}
+	fi, err := ops.Analyze(path, opt)
+	if err != nil {
+		return err
+	}
+	if *vocab {
+		if err := ops.WriteVocab(vocabPath(conf, path), fi); err != nil {
+			return err
+		}
+	}
+	return emit(conf, fi, func(w io.Writer) {
+		fi.WriteText(w)
+		if fi.Vocab != nil {
+			fi.Vocab.WriteText(w, path)
+		}
+	})
+}
+
+func cmdBatch(conf cfg.Config, args []string) error {
+	ba, err := parseBatchArgs(conf, args)
+	if err != nil {
+		return err
+	}
+	return runBatch(conf, ba)
+}
+
+// batchArgs décrit un lot : répertoire, sélection des fichiers,
+// reconstruction complète (sans le manifeste) et vocabulaire.
+type batchArgs struct {
+	Dir   string
+	Sel   ops.Selection
+	Full  bool
+	Vocab bool
+}
+
+// parseBatchArgs lit « [REPERTOIRE] [options] », pour batch comme pour le
+// menu [b] ; les valeurs par défaut viennent de conf.
+func parseBatchArgs(conf cfg.Config, args []string) (batchArgs, error) {
+	fs := newFlagSet("batch")
+	full := fs.Bool("full", false, "réanalyse tous les fichiers sans le manifeste")
+	vocab := fs.Bool("vocab", false, "fréquences de mots et n-grammes (vocab.txt)")
+	include := &listFlag{values: conf.BatchInclude}
+	exclude := &listFlag{values: conf.BatchExclude}
+	fs.Var(include, "include", "motif de fichiers retenus (répétable)")
+	fs.Var(exclude, "exclude", "motif de fichiers écartés (répétable)")
+	exts := fs.String("ext", strings.Join(batchExts(conf), ","), "extensions retenues, séparées par , (* = toutes)")
+	minSize := fs.String("min-size", "", "taille minimale (ex. 10K)")
+	maxSize := fs.String("max-size", "", "taille maximale (ex. 5M)")
+	since := fs.String("since", "", "modifiés depuis (2024-05-01 ou durée : 72h)")
+	before := fs.String("before", "", "modifiés avant (2024-05-01 ou durée : 72h)")
+	depth := fs.Int("max-depth", conf.BatchMaxDepth, "profondeur maximale (1 = répertoire seul, 0 = illimitée)")
+	symlinks := fs.String("symlinks", conf.BatchSymlinks, "liens symboliques : ignore, files, all")
+	ignoreFile := fs.String("ignore-file", conf.BatchIgnoreFile, "fichier d'exclusion lu dans chaque répertoire (\"\" = aucun)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return batchArgs{}, err
+	}
+	if len(pos) > 1 {
+		return batchArgs{}, errUsage("batch attend un seul répertoire")
+	}
+	ba := batchArgs{Dir: conf.BaseDir, Full: *full, Vocab: *vocab}
+	if len(pos) == 1 {
+		ba.Dir = pos[0]
+	}
+
+	sel := ops.Selection{
+		Include: include.values, Exclude: exclude.values,
+		MaxDepth: *depth, Symlinks: *symlinks, IgnoreFile: *ignoreFile,
+	}
+	if *depth < 0 {
+		return ba, errUsage("--max-depth ne peut pas être négatif")
+	}
+	switch *symlinks {
+	case ops.SymlinksIgnore, ops.SymlinksFiles, ops.SymlinksAll:
+	default:
+		return ba, errUsage(fmt.Sprintf("--symlinks : ignore, files ou all attendu, lu %q", *symlinks))
+	}
+	for _, e := range strings.Split(*exts, ",") {
+		if e = strings.TrimSpace(e); e == "*" {
+			sel.Exts = nil
+			break
+		} else if e != "" {
+			if !strings.HasPrefix(e, ".") {
+				e = "." + e
+			}
+			sel.Exts = append(sel.Exts, e)
+		}
+	}
+	for _, s := range []struct {
+		flag, val string
+		dst       *int64
+	}{{"--min-size", *minSize, &sel.MinSize}, {"--max-size", *maxSize, &sel.MaxSize}} {
+		if s.val == "" {
+			continue
+		}
+		if *s.dst, err = ops.ParseSize(s.val); err != nil {
+			return ba, errUsage(s.flag + " : " + err.Error())
+		}
+	}
+	now := time.Now()
+	for _, t := range []struct {
+		flag, val string
+		dst       *time.Time
+	}{{"--since", *since, &sel.Since}, {"--before", *before, &sel.Before}} {
+		if t.val == "" {
+			continue
+		}
+		if *t.dst, err = ops.ParseTime(t.val, now); err != nil {
+			return ba, errUsage(t.flag + " : " + err.Error())
+		}
+	}
+	ba.Sel = sel
+	return ba, nil
+}
+
+// listFlag est une option répétable dont la première occurrence remplace
+// les valeurs par défaut (celles de la configuration).
+type listFlag struct {
+	values []string
+	set    bool
+}
+
+func (l *listFlag) String() string { return strings.Join(l.values, ",") }
+
+func (l *listFlag) Set(v string) error {
+	if !l.set {
+		l.values, l.set = nil, true
+	}
+	l.values = append(l.values, v)
+	return nil
+}
+
+// batchExts renvoie les extensions de batch_exts, ou default_ext.
+func batchExts(conf cfg.Config) []string {
+	if len(conf.BatchExts) > 0 {
+		return conf.BatchExts
+	}
+	return []string{conf.DefaultExt}
+}
+
+func cmdWiki(conf cfg.Config, args []string) error {
+	fs := newFlagSet("wiki")
+	lang := fs.String("lang", conf.WikiLang, "langue par défaut des titres")
+	baseURL := fs.String("base-url", conf.WikiBaseURL, "adresse du wiki ({lang} = langue)")
+	offline := fs.Bool("offline", conf.WikiOffline, "sert les articles depuis le cache uniquement")
+	noCache := fs.Bool("no-cache", !conf.WikiCache, "n'utilise pas le cache disque")
+	maxAge := fs.Duration("max-age", conf.WikiCacheMaxAge, "durée sans revalidation du cache")
+	saveAs := fs.String("save-as", conf.WikiSaveFormat, "format des articles : txt, md, json, html")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return errUsage("wiki attend au moins un titre")
+	}
+	if !wiki.ValidLang(*lang) {
+		return errUsage("langue invalide : " + *lang)
+	}
+	if _, err := wiki.ParseSaveFormat(*saveAs); err != nil {
+		return errUsage(err.Error())
+	}
+	conf.WikiSaveFormat = *saveAs
+	if *offline && *noCache {
+		return errUsage("--offline et --no-cache sont incompatibles")
+	}
+	conf.WikiLang, conf.WikiBaseURL = *lang, *baseURL
+	conf.WikiOffline, conf.WikiCache, conf.WikiCacheMaxAge = *offline, !*noCache, *maxAge
+	return runWiki(conf, strings.Split(strings.Join(pos, ","), ","))
+}
+
+func cmdCrawl(conf cfg.Config, args []string) error {
+	fs := newFlagSet("crawl")
+	depth := fs.Int("depth", conf.CrawlDepth, "profondeur maximale (0 = graines seulement)")
+	maxPages := fs.Int("max-pages", conf.CrawlMaxPages, "nombre maximal d'articles téléchargés")
+	ns := fs.String("ns", "", "espaces de noms suivis, séparés par , (défaut crawl_namespaces)")
+	resume := fs.Bool("resume", false, "reprend le crawl interrompu")
+	state := fs.String("state", "", "fichier d'état (défaut OUT/crawl_state.json)")
+	saveAs := fs.String("save-as", conf.WikiSaveFormat, "format des articles : txt, md, json, html")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 && !*resume {
+		return errUsage("crawl attend au moins un titre (ou --resume)")
+	}
+	if len(pos) > 0 && *resume {
+		return errUsage("--resume reprend les titres enregistrés, sans nouveau titre")
+	}
+	if *depth < 0 || *maxPages <= 0 {
+		return errUsage("--depth doit être >= 0 et --max-pages > 0")
+	}
+	opt := wiki.CrawlOptions{
+		Seeds:      strings.Split(strings.Join(pos, ","), ","),
+		MaxDepth:   *depth,
+		MaxPages:   *maxPages,
+		Namespaces: conf.CrawlNamespaces,
+		OutDir:     conf.OutDir,
+		StatePath:  *state,
+		Resume:     *resume,
+	}
+	if opt.Format, err = wiki.ParseSaveFormat(*saveAs); err != nil {
+		return errUsage(err.Error())
+	}
+	if *ns != "" {
+		opt.Namespaces = nil
+		for _, f := range strings.Split(*ns, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return errUsage("espace de noms invalide : " + f)
+			}
+			opt.Namespaces = append(opt.Namespaces, n)
+		}
+	}
+	return runCrawl(conf, opt)
+}
+
+func cmdProc(conf cfg.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage("proc attend list, tree ou kill")
+	}
+	switch args[0] {
+	case "list":
+		fs := newFlagSet("proc list")
+		kw := fs.String("filter", "", "mot-clé sur le nom du processus")
+		top := fs.Int("top", 0, "nombre maximum de processus (0 = tous)")
+		if _, err := parseArgs(fs, args[1:]); err != nil {
+			return err
+		}
+		list, err := proc.List()
+		if err != nil {
+			return err
+		}
+		if *kw != "" {
+			list = proc.Filter(list, *kw)
+		}
+		if *top > 0 && len(list) > *top {
+			list = list[:*top]
+		}
+		return emit(conf, list, func(w io.Writer) { writeProcs(w, list) })
+
+	case "tree":
+		pos, err := parseArgs(newFlagSet("proc tree"), args[1:])
+		if err != nil {
+			return err
+		}
+		root := 0
+		if len(pos) > 1 {
+			return errUsage("proc tree attend au plus un PID")
+		}
+		if len(pos) == 1 {
+			if root, err = strconv.Atoi(pos[0]); err != nil || root <= 0 {
+				return errUsage("PID invalide : " + pos[0])
+			}
+		}
+		list, err := proc.List()
+		if err != nil {
+			return err
+		}
+		rows := list
+		if root > 0 {
+			if rows = proc.Subtree(list, root); rows == nil {
+				return fmt.Errorf("PID %d introuvable", root)
+			}
+		}
+		return emit(conf, rows, func(w io.Writer) { proc.WriteTree(w, list, root) })
+
+	case "kill":
+		fs := newFlagSet("proc kill")
+		tree := fs.Bool("tree", false, "tue aussi les descendants (enfants d'abord)")
+		force := fs.Bool("force", false, "SIGKILL au lieu de SIGTERM")
+		yes := fs.Bool("yes", false, "confirme le kill (obligatoire)")
+		pos, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 {
+			return errUsage("proc kill attend un PID")
+		}
+		pid, err := strconv.Atoi(pos[0])
+		if err != nil || pid <= 0 {
+			return errUsage("PID invalide : " + pos[0])
+		}
+		if !*tree {
+			if !*yes {
+				return errUsage("kill non confirmé : ajouter --yes")
+			}
+			return proc.Kill(pid, *force)
+		}
+
+		list, err := proc.List()
+		if err != nil {
+			return err
+		}
+		targets := proc.Subtree(list, pid)
+		if targets == nil {
+			return fmt.Errorf("PID %d introuvable", pid)
+		}
+		// aperçu de tous les PID concernés, même sans --yes
+		fmt.Fprintf(os.Stderr, "%d processus, dans l'ordre d'arrêt :\n", len(targets))
+		writeProcs(os.Stderr, targets)
+		if !*yes {
+			return errUsage("kill non confirmé : ajouter --yes")
+		}
+		return proc.KillTree(list, pid, *force)
+
+	default:
+		return errUsage("proc : action inconnue " + args[0])
+	}
+}
+
+func cmdSecure(conf cfg.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage("secure attend une action")
+	}
+	action := args[0]
+	fs := newFlagSet("secure " + action)
+	yes := fs.Bool("yes", false, "confirme la levée forcée (break)")
+	pos, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if action == "verify" {
+		if len(pos) != 0 {
+			return errUsage("secure verify ne prend pas d'argument")
+		}
+		return verifyAudit(conf)
+	}
+	if len(pos) == 0 {
+		return errUsage("secure " + action + " attend un fichier")
+	}
+	file, cmd := pos[0], pos[1:]
+	if len(cmd) > 0 && action != "lock" {
+		return errUsage("secure " + action + " attend un seul fichier")
+	}
+
+	switch action {
+	case "lock":
+		if len(cmd) > 0 {
+			return lockAndRun(conf, file, cmd)
+		}
+		// sans commande, le verrou appartient au shell appelant
+		lock, err := secure.LockWith(file, conf.OutDir, secure.LockOptions{OwnerPID: os.Getppid()})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Lock créé :", lock)
+		return secure.Log(conf.OutDir, "LOCK", lock)
+	case "unlock":
+		lock, err := secure.Unlock(file, conf.OutDir)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Lock supprimé :", lock)
+		return secure.Log(conf.OutDir, "UNLOCK", lock)
+	case "status":
+		st, err := secure.Status(file, conf.OutDir)
+		if err != nil {
+			return err
+		}
+		return emit(conf, st, func(w io.Writer) { writeLockStatus(w, st) })
+	case "break":
+		if !*yes {
+			return errUsage("levée forcée non confirmée : ajouter --yes")
+		}
+		rec, err := secure.Break(file, conf.OutDir)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Verrou levé de force, ancien propriétaire :", rec)
+	case "ro":
+		if err := secure.MakeReadOnly(file); err != nil {
+			return err
+		}
+		fmt.Println("Mode lecture-seule appliqué.")
+		return secure.Log(conf.OutDir, "CHMOD RO", file)
+	default:
+		return errUsage("secure : action inconnue " + action)
+	}
+	return nil
+}
+
+// lockAndRun garde le verrou de file le temps d'exécuter cmd (comme flock(1)).
+func lockAndRun(conf cfg.Config, file string, cmd []string) error {
+	lock, err := secure.Lock(file, conf.OutDir)
+	if err != nil {
+		return err
+	}
+	if err := secure.Log(conf.OutDir, "LOCK", lock); err != nil {
+		secure.Unlock(file, conf.OutDir)
+		return err
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	runErr := c.Run()
+	if _, err := secure.Unlock(file, conf.OutDir); err != nil {
+		return errors.Join(runErr, err)
+	}
+	return errors.Join(runErr, secure.Log(conf.OutDir, "UNLOCK", lock))
+}
+
+// verifyAudit vérifie la chaîne du journal ; une altération est une erreur.
+func verifyAudit(conf cfg.Config) error {
+	rep, err := secure.Verify(conf.OutDir)
+	if err != nil {
+		return err
+	}
+	if err := emit(conf, rep, rep.WriteText); err != nil {
+		return err
+	}
+	if !rep.OK {
+		return fmt.Errorf("journal d'audit altéré (ligne %d)", rep.Line)
+	}
+	return nil
+}
+
+// writeLockStatus affiche l'état d'un verrou.
+func writeLockStatus(w io.Writer, st secure.LockStatus) {
+	switch {
+	case !st.Exists:
+		fmt.Fprintf(w, "%s : aucun verrou\n", st.Path)
+	case st.Stale:
+		fmt.Fprintf(w, "%s : PÉRIMÉ, %s\n", st.Path, st.Record)
+	default:
+		fmt.Fprintf(w, "%s : verrouillé, %s\n", st.Path, st.Record)
+	}
+}
+
+func cmdContainers(conf cfg.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage("containers attend ls, stats, inspect ou une action")
+	}
+	docker, err := infra.NewDocker(conf.DockerHost, conf.DockerBackend)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "ls":
+		if len(args) != 1 {
+			return errUsage("containers ls ne prend pas d'argument")
+		}
+		cs, err := docker.List()
+		if err != nil {
+			return err
+		}
+		return emit(conf, cs, func(w io.Writer) {
+			fmt.Fprintf(w, "%-12s %-20s %-20s %s\n", "ID", "NOM", "IMAGE", "STATUT")
+			for _, c := range cs {
+				fmt.Fprintf(w, "%-12s %-20s %-20s %s\n", shortID(c.ID), c.Name, c.Image, c.Status)
+			}
+		})
+	case "stats":
+		fs := newFlagSet("containers stats")
+		sortBy := fs.String("sort", infra.SortCPU, "tri du tableau : cpu ou mem")
+		samples := fs.Int("samples", 1, "nombre de relevés (> 1 : min/moy/max)")
+		interval := fs.Duration("interval", 2*time.Second, "écart entre deux relevés")
+		ids, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *samples < 1 || *interval < 0 {
+			return errUsage("--samples doit être >= 1 et --interval >= 0")
+		}
+		if *sortBy != infra.SortCPU && *sortBy != infra.SortMem {
+			return errUsage("--sort attend cpu ou mem")
+		}
+
+		if *samples > 1 {
+			sums, err := docker.Sample(ids, *samples, *interval, func(i int) {
+				fmt.Fprintf(os.Stderr, "relevé %d/%d\n", i, *samples)
+			})
+			if err != nil {
+				return err
+			}
+			return emit(conf, sums, func(w io.Writer) { writeStatsSummary(w, sums) })
+		}
+		if len(ids) == 1 {
+			stat, err := docker.Stats(ids[0])
+			if err != nil {
+				return err
+			}
+			return emit(conf, stat, func(w io.Writer) { writeStatsDetails(w, stat) })
+		}
+		var list []infra.ContainerStats
+		if len(ids) == 0 {
+			list, err = docker.StatsAll(*sortBy)
+		} else {
+			for _, id := range ids {
+				stat, serr := docker.Stats(id)
+				if serr != nil {
+					return serr
+				}
+				list = append(list, stat)
+			}
+			err = infra.SortStats(list, *sortBy)
+		}
+		if err != nil {
+			return err
+		}
+		return emit(conf, list, func(w io.Writer) { writeStatsTable(w, list) })
+	case "inspect":
+		pos, err := parseArgs(newFlagSet("containers inspect"), args[1:])
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 {
+			return errUsage("containers inspect attend un ID")
+		}
+		info, err := docker.Inspect(pos[0])
+		if err != nil {
+			return err
+		}
+		return emit(conf, info, func(w io.Writer) { writeContainerInfo(w, info) })
+	default:
+		act, err := infra.ParseAction(args[0])
+		if err != nil {
+			return errUsage("containers : action inconnue " + args[0])
+		}
+		fs := newFlagSet("containers " + args[0])
+		timeout := fs.Duration("timeout", infra.DefaultStopTimeout, "délai avant SIGKILL (stop, restart)")
+		force := fs.Bool("force", false, "supprime même un conteneur en cours (rm)")
+		yes := fs.Bool("yes", false, "confirme l'action (obligatoire)")
+		pos, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 {
+			return errUsage("containers " + args[0] + " attend un ID")
+		}
+		opt := infra.ActionOptions{Timeout: *timeout, Force: *force}
+		return containerAction(conf, docker, act, pos[0], opt, func(info infra.ContainerInfo) bool {
+			// aperçu du conteneur visé, même sans --yes
+			writeContainerInfo(os.Stderr, info)
+			return *yes
+		})
+	}
+}
+
+// errNotConfirmed signale une action sur un conteneur non confirmée.
+var errNotConfirmed = errUsage("action non confirmée : ajouter --yes")
+
+// containerAction inspecte id, demande confirmation, applique act puis
+// trace l'action dans l'audit avec l'ID, le nom et l'image.
+func containerAction(conf cfg.Config, docker *infra.Docker, act infra.Action, id string,
+	opt infra.ActionOptions, confirm func(infra.ContainerInfo) bool) error {
+	info, err := docker.Inspect(id)
+	if err != nil {
+		return err
+	}
+	if !confirm(info) {
+		return errNotConfirmed
+	}
+	if err := docker.Do(act, info.ID, opt); err != nil {
+		return err
+	}
+	return secure.Log(conf.OutDir, "DOCKER "+strings.ToUpper(string(act)), info.Label())
+}
+
+// cmdDisk affiche l'occupation de tous les montages réels, ou des
+// systèmes de fichiers contenant les chemins donnés.
+func cmdDisk(conf cfg.Config, args []string) error {
+	pos, err := parseArgs(newFlagSet("disk"), args)
+	if err != nil {
+		return err
+	}
+	t := diskThresholds(conf)
+	var usages []infra.DiskUsage
+	if len(pos) == 0 {
+		if usages, err = infra.DiskReport(t); err != nil {
+			return err
+		}
+	}
+	for _, p := range pos {
+		u, err := infra.Usage(p)
+		if err != nil {
+			return err
+		}
+		u.Level = t.Level(u)
+		usages = append(usages, u)
+	}
+	return emit(conf, usages, func(w io.Writer) { writeDisks(w, usages, len(pos) > 0) })
+}
+
+// cmdWatch vérifie les règles de surveillance à intervalle régulier
+// jusqu'à Ctrl-C (ou SIGTERM), ou une seule fois avec --once.
+func cmdWatch(conf cfg.Config, args []string) error {
+	fs := newFlagSet("watch")
+	interval := fs.Duration("interval", conf.WatchInterval, "période des vérifications")
+	repeat := fs.Duration("repeat", conf.WatchRepeat, "rappel d'une alerte toujours active (0 = jamais)")
+	once := fs.Bool("once", false, "une seule vérification ; code 1 si une alerte est active")
+	var rules, sinks setFlags
+	fs.Var(&rules, "rule", "règle « TYPE CIBLE CONDITION », répétable (remplace watch_rules)")
+	fs.Var(&sinks, "sink", "destination stdout, audit, file:CHEMIN ou webhook:URL, répétable")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return errUsage("watch ne prend pas d'argument")
+	}
+	if *interval < time.Second || *repeat < 0 {
+		return errUsage("--interval doit être >= 1s et --repeat >= 0")
+	}
+	if len(rules) == 0 {
+		rules = conf.WatchRules
+	}
+	if len(rules) == 0 {
+		// par défaut : les seuils d'alerte disque du menu
+		rules = []string{
+			fmt.Sprintf("disk * free<%g", conf.DiskWarnPct),
+			fmt.Sprintf("disk * inodes<%g", conf.DiskWarnPct),
+		}
+	}
+	if len(sinks) == 0 {
+		sinks = conf.WatchSinks
+	}
+
+	w := &watch.Watcher{
+		Interval: *interval,
+		Repeat:   *repeat,
+		Errors:   func(err error) { fmt.Fprintln(os.Stderr, "watch :", err) },
+	}
+	for _, r := range rules {
+		rule, err := watch.ParseRule(r)
+		if err != nil {
+			return errUsage(err.Error())
+		}
+		w.Rules = append(w.Rules, rule)
+		if rule.Kind == watch.KindContainer && w.Docker == nil {
+			if w.Docker, err = infra.NewDocker(conf.DockerHost, conf.DockerBackend); err != nil {
+				return err
+			}
+		}
+	}
+	// stdout en JSON Lines si la sortie demandée est json ou jsonl
+	f, _ := render.ParseFormat(conf.OutputFormat)
+	jsonOut := f == render.JSON || f == render.JSONL
+	for _, sp := range sinks {
+		sink, err := watch.ParseSink(sp, conf.OutDir, jsonOut)
+		if err != nil {
+			return errUsage(err.Error())
+		}
+		w.Sinks = append(w.Sinks, sink)
+	}
+
+	if *once {
+		alerts := w.Check(context.Background())
+		if len(alerts) > 0 {
+			return fmt.Errorf("%d alerte(s) active(s)", len(alerts))
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Surveillance de %d règle(s) toutes les %s → %s (Ctrl-C pour arrêter)\n",
+		len(w.Rules), w.Interval, strings.Join(sinks, ", "))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return w.Run(ctx)
+}
+
+// diskThresholds renvoie les seuils d'alerte disque de la configuration.
+func diskThresholds(conf cfg.Config) infra.Thresholds {
+	return infra.Thresholds{WarnPct: conf.DiskWarnPct, CritPct: conf.DiskCritPct}
+}
+
+// writeDisks affiche un tableau des montages (withPath : chemin demandé
+// en première colonne).
+func writeDisks(w io.Writer, usages []infra.DiskUsage, withPath bool) {
+	if withPath {
+		fmt.Fprintf(w, "%-20s ", "CHEMIN")
+	}
+	fmt.Fprintf(w, "%-24s %-8s %10s %10s %7s %8s  %s\n",
+		"MONTAGE", "TYPE", "TAILLE", "UTILISÉ", "LIBRE%", "INODES%", "ÉTAT")
+	for _, u := range usages {
+		if withPath {
+			fmt.Fprintf(w, "%-20s ", u.Path)
+		}
+		inodes := "-"
+		if u.Inodes > 0 {
+			inodes = fmt.Sprintf("%.1f%%", u.InodesFreePct)
+		}
+		fmt.Fprintf(w, "%-24s %-8s %10s %10s %6.1f%% %8s  %s\n",
+			u.Mount, u.FSType, humanBytes(u.Total), humanBytes(u.Used), u.FreePct, inodes, u.Level)
+	}
+}
+
+// cmdConfig affiche la configuration effective et l'origine de chaque clé.
+func cmdConfig(conf cfg.Config, src cfg.Sources, args []string) error {
+	pos, err := parseArgs(newFlagSet("config"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 || pos[0] != "show" {
+		return errUsage("config attend show")
+	}
+	settings := conf.Settings(src)
+	return emit(conf, settings, func(w io.Writer) {
+		fmt.Fprintf(w, "%-15s %-30s %s\n", "CLÉ", "VALEUR", "SOURCE")
+		for _, s := range settings {
+			fmt.Fprintf(w, "%-15s %-30s %s\n", s.Key, s.Value, s.Source)
+		}
+	})
+}
+
+// humanBytes formate une taille en unités binaires (Kio, Mio…).
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d o", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cio", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shortID tronque un ID de conteneur à 12 caractères comme docker ps.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
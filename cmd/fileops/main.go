@@ -2,44 +2,85 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"fileops/internal/cfg"
 	"fileops/internal/infra"
 	"fileops/internal/ops"
 	"fileops/internal/proc"
+	"fileops/internal/render"
 	"fileops/internal/secure"
 	"fileops/internal/wiki"
 )
 
 func main() {
-	flag.String("config", "config.json", "chemin du config.json")
+	configPath := flag.String("config", "", "fichier de configuration (remplace config.json/config.txt du dossier courant)")
+	format := flag.String("format", "", "format de sortie des commandes : text, json, jsonl, csv")
+	var sets setFlags
+	flag.Var(&sets, "set", "surcharge une clé de configuration (clé=valeur, répétable)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	conf, err := cfg.Load()
+	if *format != "" {
+		sets = append(sets, "output_format="+*format)
+	}
+	conf, src, err := cfg.Load(cfg.Options{Path: *configPath, Flags: sets})
 	if err != nil {
-		log.Fatalf("Config: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Config :", err)
+		os.Exit(exitUsage)
+	}
+
+	// sous-commande non interactive (scripts, cron, CI)
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(conf, src, flag.Args()))
 	}
+	runMenu(conf)
+}
+
+// setFlags accumule les options --set répétées.
+type setFlags []string
+
+func (s *setFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *setFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
 
+// runMenu lance le menu interactif (comportement par défaut).
+func runMenu(conf cfg.Config) {
+	// le menu mélange saisies et résultats : toujours en texte
+	conf.OutputFormat = string(render.Text)
 	currentFile := conf.DefaultFile
 	in := bufio.NewScanner(os.Stdin)
 
 	for {
+		diskBanner(conf)
 		fmt.Printf(`
 ============= MENU ===============
 [f] Changer de fichier courant (actuel: %s)
 [a] Analyse fichier courant
 [b] Analyse répertoire
 [c] Analyser une page Wikipédia
+[h] Crawler Wikipédia (suivre les liens)
 [d] ProcessOps (lister, filtrer, kill)
 [e] SecureOps (verrou, read-only, audit)
-[g] ContainerOps  (Docker ps, stats)
+[g] ContainerOps  (Docker ps, stats, start/stop, inspect)
 [q] Quitter
 > `, currentFile)
 
@@ -71,37 +112,40 @@ func main() {
 			}
 
 		case "b":
-			fmt.Print("Répertoire : ")
+			fmt.Printf("Répertoire [%s] : ", conf.BaseDir)
 			if !in.Scan() {
 				continue
 			}
 			dir := in.Text()
-			if dir == "" {
-				dir = conf.BaseDir
+			fmt.Print("Filtres (ex. --ext .txt,.md --exclude tmp* --since 72h --full --vocab ; vide = aucun) : ")
+			if !in.Scan() {
+				continue
 			}
-			if err := runBatch(conf, dir); err != nil {
+			// le répertoire, qui peut contenir des espaces, suit « -- »
+			args := strings.Fields(in.Text())
+			if strings.TrimSpace(dir) != "" {
+				args = append(args, "--", dir)
+			}
+			ba, err := parseBatchArgs(conf, args)
+			if err == nil {
+				err = runBatch(conf, ba)
+			}
+			if err != nil {
 				fmt.Printf("Erreur: %v\n", err)
 			}
 
 		case "c":
-			fmt.Print("Article(s) Wikipédia (séparés par ,) : ")
+			fmt.Printf("Article(s) Wikipédia (séparés par , ; « en:Titre » pour une autre langue que %s) : ", conf.WikiLang)
 			if !in.Scan() {
 				break
 			}
-			raw := in.Text()
-			titles := strings.Split(raw, ",")
-
-			arts, err := wiki.FetchMany(titles)
-			if err != nil {
+			if err := runWiki(conf, strings.Split(in.Text(), ",")); err != nil {
 				fmt.Println("Erreur :", err)
-				break
-			}
-			for _, a := range arts {
-				if path, err := wiki.Save(a, conf.OutDir); err == nil {
-					fmt.Printf("OK  %s → %s  (%d mots)\n", a.Title, path, a.Words)
-				}
 			}
 
+		case "h":
+			crawlMenu(in, conf)
+
 		case "d":
 			processOps()
 
@@ -109,7 +153,7 @@ func main() {
 			secureMenu(conf)
 
 		case "g":
-			containerMenu()
+			containerMenu(conf)
 		case "q":
 			fmt.Println("À la prochaine")
 			return
@@ -119,28 +163,66 @@ func main() {
 	}
 }
 
-func runSingleFile(conf cfg.Config, path string) error {
-	lines, err := ops.ReadLines(path)
-	if err != nil {
-		return err
+// diskBanner signale en tête de menu les montages sous les seuils
+// d'espace ou d'inodes libres, en désignant celui qui contient OutDir.
+func diskBanner(conf cfg.Config) {
+	t := diskThresholds(conf)
+	report, _ := infra.DiskReport(t)
+	// OutDir peut ne pas exister encore : son plus proche parent existant
+	dir := conf.OutDir
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
 	}
-
-	if err := ops.PrintFileInfo(path, lines); err != nil {
-		return err
+	out, err := infra.Usage(dir)
+	if err == nil {
+		out.Level = t.Level(out)
+		if !slices.ContainsFunc(report, func(u infra.DiskUsage) bool { return u.Mount == out.Mount }) {
+			report = append(report, out)
+		}
 	}
+	for _, u := range report {
+		if u.Level == infra.LevelOK {
+			continue
+		}
+		fmt.Printf("%s!! Disque %s : %s (%s) %.1f %% libre", infra.AlertColor(u.Level), u.Level, u.Mount, u.FSType, u.FreePct)
+		if u.Inodes > 0 {
+			fmt.Printf(", inodes %.1f %% libres", u.InodesFreePct)
+		}
+		if err == nil && u.Mount == out.Mount {
+			fmt.Printf(" — contient le dossier de sortie %s", conf.OutDir)
+		}
+		fmt.Println(infra.AlertColor(infra.LevelOK))
+	}
+}
 
+// runSingleFile demande filtre, head/tail et vocabulaire puis analyse path
+// en une passe.
+func runSingleFile(conf cfg.Config, path string) error {
 	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Print("Mot-clé pour filtrer : ")
+	fmt.Print("Options du filtre (c = respecter la casse, w = mot entier, vide = aucune) : ")
 	if !scanner.Scan() {
 		return nil
 	}
-	keyword := scanner.Text()
-
-	if err := ops.FilterLines(lines, keyword, filepath.Join(conf.OutDir, "filtered.txt"), true); err != nil {
-		return err
-	}
-	if err := ops.FilterLines(lines, keyword, filepath.Join(conf.OutDir, "filtered_not.txt"), false); err != nil {
-		return err
+	var fopt ops.FilterOptions
+	flags := strings.ToLower(scanner.Text())
+	fopt.CaseSensitive = strings.Contains(flags, "c")
+	fopt.WholeWord = strings.Contains(flags, "w")
+
+	var filter *ops.Filter
+	for filter == nil {
+		fmt.Print("Filtre (mots, \"phrase\", re:/regex/, AND OR NOT, parenthèses) : ")
+		if !scanner.Scan() {
+			return nil
+		}
+		f, err := ops.ParseFilter(scanner.Text(), fopt)
+		if err != nil {
+			fmt.Println("Erreur :", err)
+			continue
+		}
+		filter = f
 	}
 
 	fmt.Print("Combien de lignes pour head/tail ? ")
@@ -150,14 +232,57 @@ func runSingleFile(conf cfg.Config, path string) error {
 	var n int
 	fmt.Sscan(scanner.Text(), &n)
 
-	_ = ops.WriteLines(lines[:min(n, len(lines))], filepath.Join(conf.OutDir, "head.txt"))
-	_ = ops.WriteLines(lines[max(0, len(lines)-n):], filepath.Join(conf.OutDir, "tail.txt"))
+	// le vocabulaire garde chaque mot distinct en mémoire : sur demande
+	fmt.Print("Vocabulaire et n-grammes (o/N) ? ")
+	if !scanner.Scan() {
+		return nil
+	}
+	withVocab := strings.HasPrefix(strings.ToLower(strings.TrimSpace(scanner.Text())), "o")
 
+	opt := ops.AnalyzeOptions{Filter: filter}
+	if withVocab {
+		opt.VocabTopN = conf.VocabTopN
+	}
+	withFilter(conf, &opt)
+	withHeadTail(conf, &opt, n, n)
+	fi, err := ops.Analyze(path, opt)
+	if err != nil {
+		return err
+	}
+	fi.WriteText(os.Stdout)
+	if !withVocab {
+		return nil
+	}
+	vocab := vocabPath(conf, path)
+	if err := ops.WriteVocab(vocab, fi); err != nil {
+		return err
+	}
+	fmt.Printf("Vocabulaire : %d mots distincts, TTR %.3f, %d hapax → %s\n",
+		fi.Vocab.Types, fi.Vocab.TTR, fi.Vocab.Hapax, vocab)
 	return nil
 }
 
-func runBatch(conf cfg.Config, dir string) error {
-	dir = strings.TrimSpace(dir)
+// vocabPath renvoie le fichier de fréquences d'une analyse simple.
+func vocabPath(conf cfg.Config, path string) string {
+	return filepath.Join(conf.OutDir, "vocab_"+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".txt")
+}
+
+// withFilter écrit filtered.txt (lignes retenues) et filtered_not.txt.
+func withFilter(conf cfg.Config, opt *ops.AnalyzeOptions) {
+	opt.Filtered = filepath.Join(conf.OutDir, "filtered.txt")
+	opt.Unfiltered = filepath.Join(conf.OutDir, "filtered_not.txt")
+}
+
+// withHeadTail écrit les head premières lignes dans head.txt et les tail dernières dans tail.txt.
+func withHeadTail(conf cfg.Config, opt *ops.AnalyzeOptions, head, tail int) {
+	opt.Head, opt.HeadOut = max(head, 0), filepath.Join(conf.OutDir, "head.txt")
+	opt.Tail, opt.TailOut = max(tail, 0), filepath.Join(conf.OutDir, "tail.txt")
+}
+
+// runBatch analyse les fichiers retenus du lot ba ; le manifeste de
+// out_dir évite de réanalyser les fichiers inchangés (sauf ba.Full).
+func runBatch(conf cfg.Config, ba batchArgs) error {
+	dir, sel := strings.TrimSpace(ba.Dir), ba.Sel
 	if dir == "" {
 		dir = conf.BaseDir
 	}
@@ -166,27 +291,178 @@ func runBatch(conf cfg.Config, dir string) error {
 		return fmt.Errorf("répertoire invalide : %v", err)
 	}
 
-	files, err := ops.ListTxt(dir)
+	files, err := sel.List(dir)
 	if err != nil {
 		return err
 	}
 	if len(files) == 0 {
-		fmt.Printf("Aucun fichier .txt trouvé dans %s\n", dir)
-		return nil
+		return emit(conf, []ops.FileInfo{}, func(w io.Writer) {
+			fmt.Fprintf(w, "Aucun fichier retenu dans %s (%s)\n", dir, sel)
+		})
+	}
+
+	bopt := ops.BatchOptions{
+		Report:   filepath.Join(conf.OutDir, "report.txt"),
+		Index:    filepath.Join(conf.OutDir, "index.txt"),
+		Merged:   filepath.Join(conf.OutDir, "merged.txt"),
+		Workers:  conf.BatchWorkers,
+		Manifest: filepath.Join(conf.OutDir, ops.ManifestName),
+		Full:     ba.Full,
+		Root:     dir,
+	}
+	if ba.Vocab {
+		bopt.Vocab, bopt.VocabTopN = filepath.Join(conf.OutDir, "vocab.txt"), conf.VocabTopN
+	}
+	res, err := ops.ProcessBatch(files, bopt)
+	if err != nil {
+		return err
+	}
+	for _, f := range res.Failed {
+		fmt.Fprintln(os.Stderr, "ÉCHEC ", f)
+	}
+	if err := emit(conf, res.Files, func(w io.Writer) {
+		fmt.Fprintf(w, "Analyse terminée : %d fichier(s) (%d réanalysé(s), %d repris du manifeste, %d retiré(s)), %d en échec → résultats dans %s\n",
+			len(res.Files), len(res.Files)-res.Reused, res.Reused, res.Removed, len(res.Failed), conf.OutDir)
+	}); err != nil {
+		return err
+	}
+	if err := res.Err(); err != nil {
+		return fmt.Errorf("%w (détail dans %s)", err, filepath.Join(conf.OutDir, "report.txt"))
+	}
+	return nil
+}
+
+// newWikiClient crée le client wiki décrit par conf.
+func newWikiClient(conf cfg.Config) *wiki.Client {
+	client := wiki.NewClient(conf.WikiLang, conf.WikiBaseURL)
+	client.APIPath = conf.WikiAPIPath
+	client.Concurrency, client.Retries = conf.WikiConcurrency, conf.WikiRetries
+	client.Limiter = wiki.NewLimiter(conf.WikiRate, max(int(conf.WikiRate), 1))
+	if conf.WikiCache || conf.WikiOffline {
+		client.Cache = wiki.NewCache(wiki.CacheDir(conf.OutDir), conf.WikiCacheMaxAge, conf.WikiOffline)
 	}
+	return client
+}
 
-	report := filepath.Join(conf.OutDir, "report.txt")
-	index := filepath.Join(conf.OutDir, "index.txt")
-	merged := filepath.Join(conf.OutDir, "merged.txt")
+// runWiki télécharge les articles demandés et les enregistre dans OutDir.
+func runWiki(conf cfg.Config, titles []string) error {
+	// Ctrl-C interrompt les téléchargements, pas le programme
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	if err := ops.ProcessBatch(files, report, index, merged); err != nil {
+	client := newWikiClient(conf)
+	res := client.FetchMany(ctx, titles)
+	saved := make([]wiki.Saved, 0, len(res.Articles))
+	format, err := wiki.ParseSaveFormat(conf.WikiSaveFormat)
+	if err != nil {
+		return err
+	}
+	// un enregistrement raté n'empêche pas celui des articles suivants
+	var saveErrs []error
+	for _, a := range res.Articles {
+		path, err := wiki.SaveAs(a, conf.OutDir, format)
+		if err != nil {
+			saveErrs = append(saveErrs, fmt.Errorf("%s:%s : %w", a.Lang, a.Title, err))
+			continue
+		}
+		saved = append(saved, wiki.Saved{Title: a.Title, Lang: a.Lang, Source: a.Source, RevID: a.RevID,
+			Cache: a.Cache, Path: path, Words: a.Words, AvgLen: a.AvgLen})
+	}
+	err = emit(conf, saved, func(w io.Writer) {
+		for _, s := range saved {
+			fmt.Fprintf(w, "OK  %s:%s → %s  (%d mots, %s)\n", s.Lang, s.Title, s.Path, s.Words, s.Source)
+		}
+		if client.Cache != nil {
+			fmt.Fprintf(w, "Cache : %s\n", client.Cache.Stats())
+		}
+	})
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Analyse terminée : %d fichiers .txt → résultats dans %s\n",
-		len(files), conf.OutDir)
+	for _, f := range res.Failed {
+		fmt.Fprintf(os.Stderr, "ÉCHEC  %s\n", f)
+	}
+	for _, e := range saveErrs {
+		fmt.Fprintf(os.Stderr, "ÉCHEC  enregistrement %v\n", e)
+	}
+	if n := len(res.Failed) + len(saveErrs); n > 0 {
+		return fmt.Errorf("%d article(s) sur %d en échec", n, n+len(saved))
+	}
 	return nil
 }
 
+// crawlMenu demande graines, profondeur et budget puis lance le crawl.
+func crawlMenu(in *bufio.Scanner, conf cfg.Config) {
+	format, err := wiki.ParseSaveFormat(conf.WikiSaveFormat)
+	if err != nil {
+		fmt.Println("Erreur :", err)
+		return
+	}
+	opt := wiki.CrawlOptions{
+		MaxDepth:   conf.CrawlDepth,
+		MaxPages:   conf.CrawlMaxPages,
+		Namespaces: conf.CrawlNamespaces,
+		OutDir:     conf.OutDir,
+		Format:     format,
+	}
+	if _, err := os.Stat(filepath.Join(conf.OutDir, "crawl_state.json")); err == nil {
+		fmt.Print("Un crawl interrompu existe, le reprendre ? yes/no : ")
+		if !in.Scan() {
+			return
+		}
+		opt.Resume = strings.ToLower(strings.TrimSpace(in.Text())) == "yes"
+	}
+	if !opt.Resume {
+		fmt.Print("Article(s) de départ (séparés par ,) : ")
+		if !in.Scan() {
+			return
+		}
+		opt.Seeds = strings.Split(in.Text(), ",")
+	}
+	fmt.Printf("Profondeur (défaut %d) : ", opt.MaxDepth)
+	if !in.Scan() {
+		return
+	}
+	fmt.Sscan(in.Text(), &opt.MaxDepth)
+	fmt.Printf("Nombre maximal d'articles (défaut %d) : ", opt.MaxPages)
+	if !in.Scan() {
+		return
+	}
+	fmt.Sscan(in.Text(), &opt.MaxPages)
+
+	if err := runCrawl(conf, opt); err != nil {
+		fmt.Println("Erreur :", err)
+	}
+}
+
+// runCrawl lance le crawl ; la progression s'affiche sur stderr.
+func runCrawl(conf cfg.Config, opt wiki.CrawlOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opt.Progress = func(p wiki.CrawlPage) {
+		if p.Path == "" {
+			fmt.Fprintf(os.Stderr, "ÉCHEC [%d] %s : %s\n", p.Depth, p.Title, p.Error)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "OK    [%d] %s → %s\n", p.Depth, p.Title, p.Path)
+	}
+	rep, err := newWikiClient(conf).Crawl(ctx, opt)
+	if errors.Is(err, context.Canceled) {
+		err = fmt.Errorf("crawl interrompu, état enregistré dans %s (reprendre avec --resume)", rep.StatePath)
+	} else if err != nil {
+		return err
+	}
+	if eerr := emit(conf, rep, func(w io.Writer) {
+		fmt.Fprintf(w, "Crawl : %d articles enregistrés, %d échecs, %d en attente, %d liens\n",
+			rep.Saved, rep.Failed, rep.Pending, rep.Edges)
+		fmt.Fprintf(w, "Graphe : %s, %s\n", rep.EdgesPath, rep.DOTPath)
+	}); eerr != nil {
+		return eerr
+	}
+	return err
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -209,6 +485,8 @@ func processOps() {
 [1] Lister les 10 premiers processus
 [2] Rechercher un mot-clé
 [3] Kill (avec confirmation)
+[4] Arbre des processus
+[5] Kill d'un sous-arbre (enfants puis parent)
 [z] Retour
 > `)
 		if !in.Scan() {
@@ -219,16 +497,8 @@ func processOps() {
 			if list, err := proc.List(); err != nil {
 				fmt.Println("Erreur :", err)
 			} else {
-				// en-tête pour faire la part des données des processus
-				fmt.Printf("\n%-6s  %s\n", "PID", "Processus")
-				fmt.Println(strings.Repeat("-", 25))
-
-				for i, p := range list {
-					if i >= 10 {
-						break
-					}
-					fmt.Printf("%-6d  %s\n", p.PID, p.Name)
-				}
+				fmt.Println()
+				writeProcs(os.Stdout, list[:min(10, len(list))])
 			}
 
 		case "2":
@@ -242,9 +512,7 @@ func processOps() {
 				fmt.Println("Erreur :", err)
 				continue
 			}
-			for _, p := range proc.Filter(all, kw) {
-				fmt.Printf("%5d  %s\n", p.PID, p.Name)
-			}
+			writeProcs(os.Stdout, proc.Filter(all, kw))
 
 		case "3":
 			fmt.Print("PID à tuer : ")
@@ -258,16 +526,13 @@ func processOps() {
 				continue
 			}
 
-			name := "(inconnu)"
-			if list, _ := proc.List(); list != nil {
-				for _, p := range list {
-					if p.PID == pid {
-						name = p.Name
-						break
-					}
-				}
+			list, _ := proc.List()
+			if p, ok := proc.Find(list, pid); ok {
+				writeProcDetails(os.Stdout, p)
+			} else {
+				fmt.Printf("PID %d : (inconnu)\n", pid)
 			}
-			fmt.Printf("Confirmer kill de %s (PID %d) ? yes/no : ", name, pid)
+			fmt.Printf("Confirmer kill du PID %d ? yes/no : ", pid)
 			if !in.Scan() {
 				continue
 			}
@@ -281,6 +546,56 @@ func processOps() {
 				fmt.Println("Processus terminé (ou déjà mort).")
 			}
 
+		case "4":
+			fmt.Print("PID racine (vide = tout) : ")
+			if !in.Scan() {
+				continue
+			}
+			root, _ := strconv.Atoi(strings.TrimSpace(in.Text()))
+			list, err := proc.List()
+			if err != nil {
+				fmt.Println("Erreur :", err)
+				continue
+			}
+			proc.WriteTree(os.Stdout, list, root)
+
+		case "5":
+			fmt.Print("PID racine du sous-arbre : ")
+			if !in.Scan() {
+				continue
+			}
+			pid, err := strconv.Atoi(strings.TrimSpace(in.Text()))
+			if err != nil || pid <= 0 {
+				fmt.Println("PID invalide")
+				continue
+			}
+			list, err := proc.List()
+			if err != nil {
+				fmt.Println("Erreur :", err)
+				continue
+			}
+			targets := proc.Subtree(list, pid)
+			if len(targets) == 0 {
+				fmt.Println("PID introuvable")
+				continue
+			}
+			proc.WriteTree(os.Stdout, list, pid)
+			fmt.Println("\nOrdre d'arrêt :")
+			writeProcs(os.Stdout, targets)
+			fmt.Printf("Confirmer kill de %d processus ? yes/no : ", len(targets))
+			if !in.Scan() {
+				continue
+			}
+			if strings.ToLower(strings.TrimSpace(in.Text())) != "yes" {
+				fmt.Println("Annulé.")
+				continue
+			}
+			if err := proc.KillTree(list, pid, false); err != nil {
+				fmt.Println("Erreur :", err)
+			} else {
+				fmt.Println("Sous-arbre terminé.")
+			}
+
 		case "z":
 			return
 		default:
@@ -289,6 +604,45 @@ func processOps() {
 	}
 }
 
+// writeProcs affiche un tableau de processus.
+func writeProcs(w io.Writer, list []proc.Process) {
+	// en-tête pour faire la part des données des processus
+	fmt.Fprintf(w, "%-7s %-7s %-10s %-2s %9s %10s %-16s %4s  %s\n",
+		"PID", "PPID", "USER", "S", "RSS", "CPU", "DÉMARRÉ", "THR", "Processus")
+	fmt.Fprintln(w, strings.Repeat("-", 90))
+	for _, p := range list {
+		fmt.Fprintf(w, "%-7d %-7d %-10.10s %-2.2s %9s %10s %-16s %4d  %s\n",
+			p.PID, p.PPID, p.User, p.State, humanBytes(p.RSS),
+			p.CPUTime.Round(time.Second), formatStart(p.StartTime), p.Threads, p.Name)
+	}
+}
+
+// writeProcDetails affiche tout ce que l'on sait d'un processus avant un kill.
+func writeProcDetails(w io.Writer, p proc.Process) {
+	fmt.Fprintf(w, "\nPID %d  %s\n", p.PID, p.Name)
+	fmt.Fprintf(w, "  parent      : %d\n", p.PPID)
+	if p.UID >= 0 {
+		fmt.Fprintf(w, "  utilisateur : %s (uid %d)\n", p.User, p.UID)
+	} else {
+		fmt.Fprintf(w, "  utilisateur : %s\n", p.User)
+	}
+	fmt.Fprintf(w, "  état        : %s, %d threads\n", p.State, p.Threads)
+	fmt.Fprintf(w, "  mémoire     : RSS %s, VSZ %s\n", humanBytes(p.RSS), humanBytes(p.VSZ))
+	fmt.Fprintf(w, "  temps CPU   : %s\n", p.CPUTime.Round(time.Millisecond))
+	fmt.Fprintf(w, "  démarré     : %s\n", formatStart(p.StartTime))
+	if len(p.Args) > 0 {
+		fmt.Fprintf(w, "  commande    : %s\n", strings.Join(p.Args, " "))
+	}
+}
+
+// formatStart affiche une date de démarrage (« ? » si inconnue).
+func formatStart(t time.Time) string {
+	if t.IsZero() {
+		return "?"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
 func secureMenu(conf cfg.Config) {
 	in := bufio.NewScanner(os.Stdin)
 	for {
@@ -297,6 +651,8 @@ func secureMenu(conf cfg.Config) {
 [1] Verrouiller un fichier
 [2] Déverrouiller un fichier
 [3] Rendre read-only
+[4] État d'un verrou / levée forcée
+[5] Vérifier le journal d'audit
 [z] Retour
 > `)
 		if !in.Scan() {
@@ -313,11 +669,19 @@ func secureMenu(conf cfg.Config) {
 				fmt.Println("Chemin vide.")
 				continue
 			}
-			if lock, err := secure.Lock(file, conf.OutDir); err != nil {
+			lock, err := secure.Lock(file, conf.OutDir)
+			if errors.Is(err, secure.ErrStale) {
+				fmt.Println("Erreur :", err)
+				if !offerBreak(in, conf, file) {
+					continue
+				}
+				lock, err = secure.Lock(file, conf.OutDir)
+			}
+			if err != nil {
 				fmt.Println("Erreur :", err)
 			} else {
 				fmt.Println("Lock créé :", lock)
-				secure.Log(conf.OutDir, "LOCK", lock)
+				logAudit(conf, "LOCK", lock)
 			}
 		case "2":
 			fmt.Print("Fichier à déverrouiller : ")
@@ -330,7 +694,7 @@ func secureMenu(conf cfg.Config) {
 				fmt.Println("Erreur :", err)
 			} else {
 				fmt.Println("Lock supprimé :", lock)
-				secure.Log(conf.OutDir, "UNLOCK", lock)
+				logAudit(conf, "UNLOCK", lock)
 			}
 		case "3":
 			fmt.Print("Fichier à passer en read-only : ")
@@ -342,7 +706,28 @@ func secureMenu(conf cfg.Config) {
 				fmt.Println("Erreur :", err)
 			} else {
 				fmt.Println("Mode lecture-seule appliqué.")
-				secure.Log(conf.OutDir, "CHMOD RO", file)
+				logAudit(conf, "CHMOD RO", file)
+			}
+		case "4":
+			fmt.Print("Fichier : ")
+			if !in.Scan() {
+				continue
+			}
+			file := strings.TrimSpace(in.Text())
+			st, err := secure.Status(file, conf.OutDir)
+			if err != nil {
+				fmt.Println("Erreur :", err)
+				continue
+			}
+			writeLockStatus(os.Stdout, st)
+			if st.Exists {
+				offerBreak(in, conf, file)
+			}
+		case "5":
+			if rep, err := secure.Verify(conf.OutDir); err != nil {
+				fmt.Println("Erreur :", err)
+			} else {
+				rep.WriteText(os.Stdout)
 			}
 		case "z":
 			return
@@ -351,13 +736,162 @@ func secureMenu(conf cfg.Config) {
 		}
 	}
 }
-func containerMenu() {
+
+// logAudit trace une action ; l'échec d'écriture est signalé, pas ignoré.
+func logAudit(conf cfg.Config, action, details string) {
+	if err := secure.Log(conf.OutDir, action, details); err != nil {
+		fmt.Println("Erreur d'audit :", err)
+	}
+}
+
+// offerBreak propose la levée forcée (tracée dans l'audit) d'un verrou.
+func offerBreak(in *bufio.Scanner, conf cfg.Config, file string) bool {
+	fmt.Print("Lever le verrou de force ? yes/no : ")
+	if !in.Scan() || strings.ToLower(strings.TrimSpace(in.Text())) != "yes" {
+		fmt.Println("Annulé.")
+		return false
+	}
+	rec, err := secure.Break(file, conf.OutDir)
+	if err != nil {
+		fmt.Println("Erreur :", err)
+		return false
+	}
+	fmt.Println("Verrou levé, ancien propriétaire :", rec)
+	return true
+}
+
+// containerActionMenu demande l'action, le conteneur et une confirmation.
+func containerActionMenu(in *bufio.Scanner, conf cfg.Config, docker *infra.Docker) {
+	fmt.Print("Action (start, stop, restart, pause, unpause, rm) : ")
+	if !in.Scan() {
+		return
+	}
+	act, err := infra.ParseAction(in.Text())
+	if err != nil {
+		fmt.Println("Erreur :", err)
+		return
+	}
+	fmt.Print("ID ou nom du conteneur : ")
+	if !in.Scan() {
+		return
+	}
+	id := strings.TrimSpace(in.Text())
+	if id == "" {
+		return
+	}
+	opt := infra.ActionOptions{Timeout: infra.DefaultStopTimeout}
+	switch act {
+	case infra.ActStop, infra.ActRestart:
+		fmt.Printf("Délai avant SIGKILL [%s] : ", infra.DefaultStopTimeout)
+		if !in.Scan() {
+			return
+		}
+		if d, err := time.ParseDuration(strings.TrimSpace(in.Text())); err == nil && d >= 0 {
+			opt.Timeout = d
+		}
+	case infra.ActRemove:
+		fmt.Print("Forcer la suppression s'il tourne ? yes/no : ")
+		if !in.Scan() {
+			return
+		}
+		opt.Force = strings.ToLower(strings.TrimSpace(in.Text())) == "yes"
+	}
+
+	err = containerAction(conf, docker, act, id, opt, func(info infra.ContainerInfo) bool {
+		writeContainerInfo(os.Stdout, info)
+		fmt.Printf("Confirmer %s de %s ? yes/no : ", act, info.Name)
+		return in.Scan() && strings.ToLower(strings.TrimSpace(in.Text())) == "yes"
+	})
+	switch {
+	case errors.Is(err, errNotConfirmed):
+		fmt.Println("Annulé.")
+	case err != nil:
+		fmt.Println("Erreur :", err)
+	default:
+		fmt.Printf("%s : %s effectué.\n", id, act)
+	}
+}
+
+// writeContainerInfo affiche le résultat de inspect.
+func writeContainerInfo(w io.Writer, c infra.ContainerInfo) {
+	fmt.Fprintf(w, "\n%s  (%s)\n", c.Name, shortID(c.ID))
+	fmt.Fprintf(w, "  image     : %s\n", c.Image)
+	fmt.Fprintf(w, "  commande  : %s\n", c.Command)
+	fmt.Fprintf(w, "  état      : %s", c.Status)
+	switch {
+	case c.Status == "running" || c.Status == "paused":
+		fmt.Fprintf(w, " (PID %d, depuis %s)", c.PID, c.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	case !c.FinishedAt.IsZero():
+		fmt.Fprintf(w, " (code %d, le %s)", c.ExitCode, c.FinishedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  créé      : %s, %d redémarrage(s)\n", c.Created.Local().Format("2006-01-02 15:04:05"), c.RestartCount)
+	if len(c.Ports) > 0 {
+		fmt.Fprintf(w, "  ports     : %s\n", strings.Join(c.Ports, ", "))
+	}
+	for _, m := range c.Mounts {
+		fmt.Fprintf(w, "  volume    : %s\n", m)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Networks)) {
+		fmt.Fprintf(w, "  réseau    : %s %s\n", name, c.Networks[name])
+	}
+}
+
+// writeStatsTable affiche un relevé par conteneur, une ligne chacun.
+func writeStatsTable(w io.Writer, list []infra.ContainerStats) {
+	fmt.Fprintf(w, "%-12s %-20s %7s %21s %6s %21s %21s %5s\n",
+		"ID", "NOM", "CPU%", "MÉMOIRE / LIMITE", "MEM%", "RÉSEAU RX / TX", "DISQUE L / É", "PIDS")
+	for _, s := range list {
+		fmt.Fprintf(w, "%-12s %-20.20s %6.2f%% %21s %5.1f%% %21s %21s %5d\n",
+			shortID(s.ID), s.Name, s.CPUPercent,
+			humanBytes(s.MemUsage)+" / "+humanBytes(s.MemLimit), s.MemPercent,
+			humanBytes(s.NetRx)+" / "+humanBytes(s.NetTx),
+			humanBytes(s.BlockRead)+" / "+humanBytes(s.BlockWrite), s.PIDs)
+	}
+}
+
+// writeStatsDetails affiche le relevé complet d'un conteneur.
+func writeStatsDetails(w io.Writer, s infra.ContainerStats) {
+	name := s.Name
+	if name == "" {
+		name = shortID(s.ID)
+	}
+	fmt.Fprintf(w, "\n%s  (%s, %s)\n", name, shortID(s.ID), s.Time.Format("15:04:05"))
+	fmt.Fprintf(w, "  CPU     : %.2f%%\n", s.CPUPercent)
+	fmt.Fprintf(w, "  mémoire : %s / %s (%.2f%%)\n", humanBytes(s.MemUsage), humanBytes(s.MemLimit), s.MemPercent)
+	fmt.Fprintf(w, "  réseau  : reçu %s, émis %s\n", humanBytes(s.NetRx), humanBytes(s.NetTx))
+	fmt.Fprintf(w, "  disque  : lu %s, écrit %s\n", humanBytes(s.BlockRead), humanBytes(s.BlockWrite))
+	fmt.Fprintf(w, "  PIDs    : %d\n", s.PIDs)
+}
+
+// writeStatsSummary affiche min/moy/max de chaque conteneur échantillonné.
+func writeStatsSummary(w io.Writer, sums []infra.StatsSummary) {
+	fmt.Fprintf(w, "%-12s %-20s %4s %-22s %-32s %s\n",
+		"ID", "NOM", "N", "CPU% min/moy/max", "MÉMOIRE min/moy/max", "PIDS min/moy/max")
+	for _, s := range sums {
+		mem := humanBytes(uint64(s.MemUsage.Min)) + "/" + humanBytes(uint64(s.MemUsage.Avg)) +
+			"/" + humanBytes(uint64(s.MemUsage.Max))
+		fmt.Fprintf(w, "%-12s %-20.20s %4d %-22s %-32s %.0f/%.1f/%.0f\n",
+			shortID(s.ID), s.Name, s.Samples, s.CPUPercent, mem, s.PIDs.Min, s.PIDs.Avg, s.PIDs.Max)
+	}
+}
+
+func containerMenu(conf cfg.Config) {
+	docker, err := infra.NewDocker(conf.DockerHost, conf.DockerBackend)
+	if err != nil {
+		fmt.Println("Erreur :", err)
+		return
+	}
 	in := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Print(`
 ----- ContainerOps -----
 [1] Lister les conteneurs actifs
-[2] Stats CPU/Mem d’un conteneur
+[2] Stats détaillées d’un conteneur
+[3] Tableau de tous les conteneurs (tri CPU ou mémoire)
+[4] Échantillonnage (min/moy/max)
+[5] Inspecter un conteneur
+[6] Action : start, stop, restart, pause, unpause, rm
 [z] Retour
 > `)
 		if !in.Scan() {
@@ -365,10 +899,10 @@ func containerMenu() {
 		}
 		switch strings.TrimSpace(in.Text()) {
 		case "1":
-			cs, err := infra.List()
+			cs, err := docker.List()
 			if err != nil {
 				fmt.Println("Erreur :", err)
-				return
+				continue
 			}
 			if len(cs) == 0 {
 				fmt.Println("Aucun conteneur en cours.")
@@ -377,7 +911,7 @@ func containerMenu() {
 			fmt.Printf("\n%-12s %-20s %-20s %s\n", "ID", "NOM", "IMAGE", "STATUT")
 			for _, c := range cs {
 				fmt.Printf("%-12s %-20s %-20s %s\n",
-					c.ID[:12], c.Name, c.Image, c.Status)
+					shortID(c.ID), c.Name, c.Image, c.Status)
 			}
 		case "2":
 			fmt.Print("ID ou nom du conteneur : ")
@@ -388,11 +922,73 @@ func containerMenu() {
 			if id == "" {
 				continue
 			}
-			if stat, err := infra.Stats(id); err != nil {
+			if stat, err := docker.Stats(id); err != nil {
+				fmt.Println("Erreur :", err)
+			} else {
+				writeStatsDetails(os.Stdout, stat)
+			}
+		case "3":
+			fmt.Print("Tri (cpu/mem) [cpu] : ")
+			if !in.Scan() {
+				continue
+			}
+			by := strings.TrimSpace(in.Text())
+			if by == "" {
+				by = infra.SortCPU
+			}
+			list, err := docker.StatsAll(by)
+			if err != nil {
+				fmt.Println("Erreur :", err)
+				continue
+			}
+			if len(list) == 0 {
+				fmt.Println("Aucun conteneur en cours.")
+				continue
+			}
+			fmt.Println()
+			writeStatsTable(os.Stdout, list)
+		case "4":
+			fmt.Print("Nombre de relevés [5] : ")
+			if !in.Scan() {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(in.Text()))
+			if err != nil || n < 1 {
+				n = 5
+			}
+			fmt.Print("Intervalle [2s] : ")
+			if !in.Scan() {
+				continue
+			}
+			interval, err := time.ParseDuration(strings.TrimSpace(in.Text()))
+			if err != nil || interval < 0 {
+				interval = 2 * time.Second
+			}
+			sums, err := docker.Sample(nil, n, interval, func(i int) {
+				fmt.Printf("relevé %d/%d\n", i, n)
+			})
+			if err != nil {
+				fmt.Println("Erreur :", err)
+				continue
+			}
+			fmt.Println()
+			writeStatsSummary(os.Stdout, sums)
+		case "5":
+			fmt.Print("ID ou nom du conteneur : ")
+			if !in.Scan() {
+				continue
+			}
+			id := strings.TrimSpace(in.Text())
+			if id == "" {
+				continue
+			}
+			if info, err := docker.Inspect(id); err != nil {
 				fmt.Println("Erreur :", err)
 			} else {
-				fmt.Println("CPU%  MEM% :", stat)
+				writeContainerInfo(os.Stdout, info)
 			}
+		case "6":
+			containerActionMenu(in, conf, docker)
 		case "z":
 			return
 		default:
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"fileops/internal/cfg"
+	"fileops/internal/render"
+)
+
+// emit affiche v sur stdout au format de conf ; text produit la sortie texte.
+func emit(conf cfg.Config, v any, text func(w io.Writer)) error {
+	f, err := render.ParseFormat(conf.OutputFormat)
+	if err != nil {
+		return err
+	}
+	if f == render.Text {
+		text(os.Stdout)
+		return nil
+	}
+	return render.Write(os.Stdout, f, v)
+}
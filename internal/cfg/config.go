@@ -1,50 +1,633 @@
 package cfg
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
-	"flag"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config regroupe toutes les clés possibles.
+// Le tag cfg:"path" marque les chemins : relatifs dans un fichier de
+// configuration, ils sont résolus par rapport au dossier de ce fichier.
 type Config struct {
-	DefaultFile string `json:"default_file"`
-	BaseDir     string `json:"base_dir"`
-	OutDir      string `json:"out_dir"`
+	DefaultFile string `json:"default_file" cfg:"path"`
+	BaseDir     string `json:"base_dir" cfg:"path"`
+	OutDir      string `json:"out_dir" cfg:"path"`
 	DefaultExt  string `json:"default_ext"`
 	WikiLang    string `json:"wiki_lang"`
-	ProcessTopN int    `json:"process_top_n"`
+	// WikiBaseURL : adresse du wiki, {lang} est remplacé par la langue
+	WikiBaseURL string `json:"wiki_base_url"`
+	// WikiAPIPath : chemin de api.php ("" = page HTML seulement)
+	WikiAPIPath string `json:"wiki_api_path"`
+	// WikiCache : cache disque des pages sous OutDir/cache/wiki ;
+	// WikiCacheMaxAge : durée sans revalidation ; WikiOffline : cache seul
+	WikiCache       bool          `json:"wiki_cache"`
+	WikiCacheMaxAge time.Duration `json:"wiki_cache_max_age"`
+	WikiOffline     bool          `json:"wiki_offline"`
+	// WikiConcurrency : téléchargements simultanés ; WikiRate : requêtes
+	// par seconde (0 = illimité) ; WikiRetries : essais sur 429/5xx
+	WikiConcurrency int     `json:"wiki_concurrency"`
+	WikiRate        float64 `json:"wiki_rate"`
+	WikiRetries     int     `json:"wiki_retries"`
+	// WikiSaveFormat : format des articles enregistrés (txt, md, json, html)
+	WikiSaveFormat string `json:"wiki_save_format"`
+	// DockerHost : unix:///chemin ou tcp://hôte:port ("" = DOCKER_HOST ou
+	// /var/run/docker.sock) ; DockerBackend : auto (API puis CLI), api, cli
+	DockerHost    string `json:"docker_host"`
+	DockerBackend string `json:"docker_backend"`
+	// DiskWarnPct, DiskCritPct : % d'espace ou d'inodes libres sous
+	// lesquels un disque est signalé en alerte, puis en critique
+	DiskWarnPct float64 `json:"disk_warn_pct"`
+	DiskCritPct float64 `json:"disk_crit_pct"`
+	// Watch : période des vérifications, règles (« disk * free<10 » ;
+	// vide = disques sous disk_warn_pct), destinations des alertes
+	// (stdout, audit, file:CHEMIN, webhook:URL) et rappel d'une alerte
+	// toujours active (0 = jamais)
+	WatchInterval time.Duration `json:"watch_interval"`
+	WatchRules    []string      `json:"watch_rules"`
+	WatchSinks    []string      `json:"watch_sinks"`
+	WatchRepeat   time.Duration `json:"watch_repeat"`
+	// Crawl : profondeur, budget d'articles et espaces de noms suivis
+	CrawlDepth      int   `json:"crawl_depth"`
+	CrawlMaxPages   int   `json:"crawl_max_pages"`
+	CrawlNamespaces []int `json:"crawl_namespaces"`
+	// BatchWorkers : analyses simultanées de batch et du menu [b]
+	BatchWorkers int `json:"batch_workers"`
+	// Batch : sélection des fichiers — motifs inclus/exclus (« *.log »,
+	// « logs/**/*.txt »), extensions (vide = default_ext), profondeur
+	// (0 = illimitée), liens symboliques (ignore, files, all) et fichier
+	// d'exclusion façon .gitignore ("" = aucun)
+	BatchInclude    []string `json:"batch_include"`
+	BatchExclude    []string `json:"batch_exclude"`
+	BatchExts       []string `json:"batch_exts"`
+	BatchMaxDepth   int      `json:"batch_max_depth"`
+	BatchSymlinks   string   `json:"batch_symlinks"`
+	BatchIgnoreFile string   `json:"batch_ignore_file"`
+	ProcessTopN     int      `json:"process_top_n"`
+	// VocabTopN : taille des tableaux de fréquences (mots, n-grammes)
+	VocabTopN int `json:"vocab_top_n"`
+	// OutputFormat : text, json, jsonl ou csv (sous-commandes)
+	OutputFormat string `json:"output_format"`
 }
 
-func Load() (Config, error) {
-	// 1) valeurs par défaut
-	cfg := Config{
-		DefaultFile: "data/input.txt",
-		BaseDir:     "data",
-		OutDir:      "out",
-		DefaultExt:  ".txt",
-		WikiLang:    "fr",
-		ProcessTopN: 10,
+// Defaults renvoie les valeurs intégrées.
+func Defaults() Config {
+	return Config{
+		DefaultFile:     "data/input.txt",
+		BaseDir:         "data",
+		OutDir:          "out",
+		DefaultExt:      ".txt",
+		WikiLang:        "fr",
+		WikiBaseURL:     "https://{lang}.wikipedia.org",
+		WikiAPIPath:     "/w/api.php",
+		WikiCache:       true,
+		WikiCacheMaxAge: 24 * time.Hour,
+		WikiConcurrency: 4,
+		WikiRate:        5,
+		WikiRetries:     3,
+		WikiSaveFormat:  "txt",
+		DockerBackend:   "auto",
+		DiskWarnPct:     10,
+		DiskCritPct:     5,
+		WatchInterval:   30 * time.Second,
+		WatchSinks:      []string{"stdout"},
+		CrawlDepth:      1,
+		CrawlMaxPages:   50,
+		CrawlNamespaces: []int{0},
+		BatchWorkers:    4,
+		BatchSymlinks:   "files",
+		BatchIgnoreFile: ".fileopsignore",
+		ProcessTopN:     10,
+		VocabTopN:       20,
+		OutputFormat:    "text",
+	}
+}
+
+// Couches de configuration, de la moins à la plus prioritaire.
+const (
+	LayerDefault = "défaut"
+	LayerSystem  = "système"
+	LayerUser    = "utilisateur"
+	LayerProject = "projet"
+	LayerEnv     = "env"
+	LayerFlag    = "option"
+)
+
+// EnvPrefix précède le nom des variables d'environnement (FILEOPS_OUT_DIR…).
+const EnvPrefix = "FILEOPS_"
+
+// Source indique d'où vient la valeur effective d'une clé.
+type Source struct {
+	Layer string `json:"layer"`
+	Name  string `json:"name,omitempty"` // fichier, variable ou option
+	Line  int    `json:"line,omitempty"`
+}
+
+func (s Source) String() string {
+	switch {
+	case s.Line > 0:
+		return fmt.Sprintf("%s %s:%d", s.Layer, s.Name, s.Line)
+	case s.Name != "":
+		return s.Layer + " " + s.Name
+	default:
+		return s.Layer
+	}
+}
+
+// Sources associe chaque clé à sa source.
+type Sources map[string]Source
+
+// Options décrit où chercher la configuration. Les dossiers vides
+// prennent les emplacements standard.
+type Options struct {
+	// Path (--config) remplace la couche projet ; il doit exister.
+	Path string
+	// Flags : paires clé=valeur issues de la ligne de commande.
+	Flags []string
+	// Env : variables d'environnement (nil = os.Environ()).
+	Env []string
+
+	SystemDir  string // défaut /etc/fileops
+	UserDir    string // défaut $XDG_CONFIG_HOME/fileops
+	ProjectDir string // défaut dossier courant
+}
+
+// Noms de fichiers cherchés dans chaque dossier de configuration.
+var fileNames = []string{"config.json", "config.txt"}
+
+// Load construit la configuration effective :
+// défauts → système → utilisateur → projet → FILEOPS_* → options.
+func Load(opt Options) (Config, Sources, error) {
+	c := Defaults()
+	src := make(Sources)
+	for _, f := range fields() {
+		src[f.key] = Source{Layer: LayerDefault}
+	}
+
+	if opt.SystemDir == "" {
+		opt.SystemDir = "/etc/fileops"
+	}
+	if opt.UserDir == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			opt.UserDir = filepath.Join(dir, "fileops")
+		}
+	}
+	if opt.ProjectDir == "" {
+		opt.ProjectDir = "."
+	}
+
+	layers := []struct{ layer, dir string }{
+		{LayerSystem, opt.SystemDir},
+		{LayerUser, opt.UserDir},
+	}
+	if opt.Path == "" {
+		layers = append(layers, struct{ layer, dir string }{LayerProject, opt.ProjectDir})
+	}
+	for _, l := range layers {
+		if l.dir == "" {
+			continue
+		}
+		for _, name := range fileNames {
+			if err := loadFile(&c, src, l.layer, filepath.Join(l.dir, name), true); err != nil {
+				return c, src, err
+			}
+		}
+	}
+	if opt.Path != "" {
+		if err := loadFile(&c, src, LayerProject, opt.Path, false); err != nil {
+			return c, src, err
+		}
+	}
+
+	env := opt.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	for _, kv := range env {
+		name, val, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, EnvPrefix)
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(key)
+		// les autres FILEOPS_* (clé d'audit…) ne sont pas des clés de config
+		if lookup(key) == nil {
+			continue
+		}
+		if err := set(&c, key, val); err != nil {
+			return c, src, fmt.Errorf("variable %s : %w", name, err)
+		}
+		src[key] = Source{Layer: LayerEnv, Name: name}
 	}
 
-	path := flag.Lookup("config").Value.String()
-	if path == "" {
-		path = "config.json"
+	for _, kv := range opt.Flags {
+		key, val, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return c, src, fmt.Errorf("option %q : clé=valeur attendu", kv)
+		}
+		if lookup(key) == nil {
+			return c, src, fmt.Errorf("option %q : clé inconnue « %s »", kv, key)
+		}
+		if err := set(&c, key, val); err != nil {
+			return c, src, fmt.Errorf("option %s : %w", key, err)
+		}
+		src[key] = Source{Layer: LayerFlag, Name: key}
 	}
 
+	return c, src, c.Validate(src)
+}
+
+// ERREURS
+
+// LineError est une erreur située dans un fichier de configuration.
+type LineError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%s:%d : %v", e.File, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// loadFile applique un fichier JSON ou clé=valeur ; optional ignore un
+// fichier absent.
+func loadFile(c *Config, src Sources, layer, path string, optional bool) error {
 	b, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("config : %w", err)
+	}
+	dir := filepath.Dir(path)
+	apply := func(key string, line int, setFn func() error) error {
+		if lookup(key) == nil {
+			return &LineError{path, line, fmt.Errorf("clé inconnue « %s »", key)}
+		}
+		if prev, ok := src[key]; ok && prev.Layer == layer && prev.Name == path {
+			return &LineError{path, line, fmt.Errorf("clé « %s » déjà définie ligne %d", key, prev.Line)}
 		}
-		return cfg, err
+		if err := setFn(); err != nil {
+			return &LineError{path, line, fmt.Errorf("%s : %w", key, err)}
+		}
+		resolvePath(c, key, dir)
+		src[key] = Source{Layer: layer, Name: path, Line: line}
+		return nil
+	}
+
+	if isJSON(path, b) {
+		return loadJSON(c, path, b, apply)
 	}
+	return loadKeyValue(c, path, b, apply)
+}
+
+func isJSON(path string, b []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return true
+	case ".txt", ".conf", ".ini", ".env":
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
 
-	_ = json.Unmarshal(b, &cfg)
+type applyFunc func(key string, line int, set func() error) error
 
-	if !filepath.IsAbs(cfg.OutDir) {
-		cfg.OutDir = filepath.Clean(filepath.Join(filepath.Dir(path), cfg.OutDir))
+// loadJSON lit un objet JSON plat en gardant la ligne de chaque clé.
+func loadJSON(c *Config, path string, b []byte, apply applyFunc) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	syntax := func(err error) error {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			return &LineError{path, lineAt(b, se.Offset), fmt.Errorf("JSON invalide : %v", se)}
+		}
+		return &LineError{path, lineAt(b, dec.InputOffset()), fmt.Errorf("JSON invalide : %v", err)}
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return syntax(err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return &LineError{path, 1, errors.New("objet JSON attendu")}
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return syntax(err)
+		}
+		key := tok.(string)
+		line := lineAt(b, dec.InputOffset())
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return syntax(err)
+		}
+		if err := apply(key, line, func() error { return setJSON(c, key, raw) }); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return syntax(err)
+	}
+	if _, err := dec.Token(); err == nil {
+		return &LineError{path, lineAt(b, dec.InputOffset()), errors.New("contenu après l'objet JSON")}
+	}
+	return nil
+}
+
+// loadKeyValue lit des lignes « clé=valeur » ; # et ; ouvrent un commentaire.
+func loadKeyValue(c *Config, path string, b []byte, apply applyFunc) error {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") || strings.HasPrefix(l, ";") {
+			continue
+		}
+		key, val, ok := strings.Cut(l, "=")
+		if !ok {
+			return &LineError{path, n, fmt.Errorf("« clé=valeur » attendu, lu %q", l)}
+		}
+		key, val = strings.TrimSpace(key), unquote(strings.TrimSpace(val))
+		if err := apply(key, n, func() error { return set(c, key, val) }); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// lineAt convertit un décalage en numéro de ligne (à partir de 1).
+func lineAt(b []byte, off int64) int {
+	off = min(max(off, 0), int64(len(b)))
+	return bytes.Count(b[:off], []byte{'\n'}) + 1
+}
+
+// CHAMPS
+
+type field struct {
+	key    string
+	name   string
+	index  []int
+	isPath bool
+}
+
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		out = append(out, field{key: key, name: f.Name, index: f.Index, isPath: f.Tag.Get("cfg") == "path"})
+	}
+	return out
+}
+
+func lookup(key string) *field {
+	for _, f := range fields() {
+		if f.key == key {
+			return &f
+		}
+	}
+	return nil
+}
+
+// Keys renvoie toutes les clés connues, triées.
+func Keys() []string {
+	var keys []string
+	for _, f := range fields() {
+		keys = append(keys, f.key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set affecte une valeur texte (fichier clé=valeur, env, option).
+func set(c *Config, key, val string) error {
+	f := lookup(key)
+	if f == nil {
+		return fmt.Errorf("clé inconnue « %s »", key)
+	}
+	v := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("durée invalide %q (ex. 30s, 5m)", val)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(val)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("entier attendu, lu %q", val)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		x, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("nombre attendu, lu %q", val)
+		}
+		v.SetFloat(x)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("booléen attendu (true/false), lu %q", val)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, it := range strings.Split(val, ",") {
+			if it = strings.TrimSpace(it); it != "" {
+				items = append(items, it)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Int:
+		var items []int
+		for _, it := range strings.Split(val, ",") {
+			if it = strings.TrimSpace(it); it == "" {
+				continue
+			}
+			n, err := strconv.Atoi(it)
+			if err != nil {
+				return fmt.Errorf("liste d'entiers attendue, lu %q", val)
+			}
+			items = append(items, n)
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("type %s non géré", v.Type())
+	}
+	return nil
+}
+
+// setJSON affecte une valeur JSON ; une chaîne est lue comme en clé=valeur
+// (durées « 5m », listes « a,b »), le reste doit avoir le type du champ.
+func setJSON(c *Config, key string, raw json.RawMessage) error {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return set(c, key, s)
+	}
+	f := lookup(key)
+	v := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	if v.Kind() == reflect.String || v.Type() == durationType {
+		return fmt.Errorf("chaîne attendue, lu %s", raw)
+	}
+	tmp := reflect.New(v.Type())
+	if err := json.Unmarshal(raw, tmp.Interface()); err != nil {
+		return fmt.Errorf("%s attendu, lu %s", v.Type(), raw)
+	}
+	v.Set(tmp.Elem())
+	return nil
+}
+
+// resolvePath rend absolu-relatif au dossier du fichier un chemin relatif.
+func resolvePath(c *Config, key, dir string) {
+	f := lookup(key)
+	if f == nil || !f.isPath {
+		return
+	}
+	v := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	if p := v.String(); p != "" && !filepath.IsAbs(p) {
+		v.SetString(filepath.Clean(filepath.Join(dir, p)))
+	}
+}
+
+// Get renvoie la valeur d'une clé sous forme de texte.
+func (c Config) Get(key string) (string, bool) {
+	f := lookup(key)
+	if f == nil {
+		return "", false
+	}
+	v := reflect.ValueOf(c).FieldByIndex(f.index)
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return x.String(), true
+	case []string:
+		return strings.Join(x, ","), true
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return strings.Join(parts, ","), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// Setting est une ligne de « fileops config show ».
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings liste toutes les clés avec leur valeur effective et leur source.
+func (c Config) Settings(src Sources) []Setting {
+	var out []Setting
+	for _, k := range Keys() {
+		v, _ := c.Get(k)
+		out = append(out, Setting{Key: k, Value: v, Source: src[k].String()})
+	}
+	return out
+}
+
+// VALIDATION
+
+var langRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]+)*$`)
+
+// Validate vérifie les valeurs et situe chaque erreur dans sa source.
+func (c Config) Validate(src Sources) error {
+	var errs []error
+	check := func(key string, ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s (%s) : %s", key, src[key], fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check("out_dir", c.OutDir != "", "ne peut pas être vide")
+	check("base_dir", c.BaseDir != "", "ne peut pas être vide")
+	check("default_ext", strings.HasPrefix(c.DefaultExt, "."), "doit commencer par « . », lu %q", c.DefaultExt)
+	check("wiki_lang", langRe.MatchString(c.WikiLang), "code de langue invalide %q (ex. fr, en)", c.WikiLang)
+	u, err := url.Parse(strings.ReplaceAll(c.WikiBaseURL, "{lang}", "xx"))
+	check("wiki_base_url", err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"URL http(s) attendue, lu %q", c.WikiBaseURL)
+	check("wiki_api_path", c.WikiAPIPath == "" || strings.HasPrefix(c.WikiAPIPath, "/"),
+		"doit commencer par « / », lu %q", c.WikiAPIPath)
+	check("wiki_cache_max_age", c.WikiCacheMaxAge >= 0, "ne peut pas être négatif")
+	check("wiki_concurrency", c.WikiConcurrency >= 1 && c.WikiConcurrency <= 64, "entre 1 et 64, lu %d", c.WikiConcurrency)
+	check("wiki_rate", c.WikiRate >= 0, "ne peut pas être négatif (0 = illimité)")
+	check("wiki_retries", c.WikiRetries >= 0 && c.WikiRetries <= 10, "entre 0 et 10, lu %d", c.WikiRetries)
+	switch c.WikiSaveFormat {
+	case "txt", "md", "json", "html":
+	default:
+		check("wiki_save_format", false, "txt, md, json ou html attendu, lu %q", c.WikiSaveFormat)
+	}
+	switch c.DockerBackend {
+	case "auto", "api", "cli":
+	default:
+		check("docker_backend", false, "auto, api ou cli attendu, lu %q", c.DockerBackend)
+	}
+	check("docker_host", c.DockerHost == "" || strings.HasPrefix(c.DockerHost, "unix://") ||
+		strings.HasPrefix(c.DockerHost, "tcp://"), "unix:// ou tcp:// attendu, lu %q", c.DockerHost)
+	check("disk_warn_pct", c.DiskWarnPct >= 0 && c.DiskWarnPct <= 100, "entre 0 et 100, lu %g", c.DiskWarnPct)
+	check("disk_crit_pct", c.DiskCritPct >= 0 && c.DiskCritPct <= c.DiskWarnPct,
+		"entre 0 et disk_warn_pct (%g), lu %g", c.DiskWarnPct, c.DiskCritPct)
+	check("watch_interval", c.WatchInterval >= time.Second, "au moins 1s, lu %s", c.WatchInterval)
+	check("watch_repeat", c.WatchRepeat >= 0, "ne peut pas être négatif")
+	check("watch_sinks", len(c.WatchSinks) > 0, "au moins une destination (stdout, audit, file:, webhook:)")
+	check("crawl_depth", c.CrawlDepth >= 0, "ne peut pas être négatif")
+	check("crawl_max_pages", c.CrawlMaxPages > 0, "doit être > 0, lu %d", c.CrawlMaxPages)
+	check("batch_workers", c.BatchWorkers >= 1 && c.BatchWorkers <= 64, "entre 1 et 64, lu %d", c.BatchWorkers)
+	for _, ext := range c.BatchExts {
+		check("batch_exts", strings.HasPrefix(ext, "."), "doit commencer par « . », lu %q", ext)
+	}
+	for _, p := range append(slices.Clone(c.BatchInclude), c.BatchExclude...) {
+		_, err := path.Match(strings.ReplaceAll(p, "**", "*"), "")
+		check("batch_include/batch_exclude", err == nil, "motif invalide %q", p)
+	}
+	check("batch_max_depth", c.BatchMaxDepth >= 0, "ne peut pas être négatif (0 = illimitée)")
+	switch c.BatchSymlinks {
+	case "ignore", "files", "all":
+	default:
+		check("batch_symlinks", false, "ignore, files ou all attendu, lu %q", c.BatchSymlinks)
+	}
+	check("batch_ignore_file", !strings.ContainsAny(c.BatchIgnoreFile, `/\`), "nom de fichier attendu, lu %q", c.BatchIgnoreFile)
+	check("process_top_n", c.ProcessTopN > 0, "doit être > 0, lu %d", c.ProcessTopN)
+	check("vocab_top_n", c.VocabTopN > 0, "doit être > 0, lu %d", c.VocabTopN)
+	switch strings.ToLower(c.OutputFormat) {
+	case "text", "json", "jsonl", "ndjson", "csv":
+	default:
+		check("output_format", false, "text, json, jsonl ou csv attendu, lu %q", c.OutputFormat)
 	}
-	return cfg, nil
+	return errors.Join(errs...)
 }
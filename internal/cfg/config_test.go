@@ -0,0 +1,167 @@
+package cfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dirs crée les dossiers système, utilisateur et projet d'un test.
+func dirs(t *testing.T) Options {
+	t.Helper()
+	root := t.TempDir()
+	opt := Options{Env: []string{}}
+	for _, d := range []*string{&opt.SystemDir, &opt.UserDir, &opt.ProjectDir} {
+		dir, err := os.MkdirTemp(root, "cfg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		*d = dir
+	}
+	return opt
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	opt := dirs(t)
+	sys := filepath.Join(opt.SystemDir, "config.txt")
+	usr := filepath.Join(opt.UserDir, "config.json")
+	prj := filepath.Join(opt.ProjectDir, "config.txt")
+	writeFile(t, sys, "wiki_lang=de\nbatch_workers=2\nout_dir=sortie\n")
+	writeFile(t, usr, "{\n  \"wiki_lang\": \"en\",\n  \"watch_interval\": \"1m\",\n  \"batch_exts\": [\".log\", \".md\"]\n}\n")
+	writeFile(t, prj, "# projet\nwiki_lang = 'es'\n; commentaire\nprocess_top_n=5\n")
+	opt.Env = []string{"FILEOPS_PROCESS_TOP_N=7", "FILEOPS_AUDIT_KEY=secret", "PATH=/bin"}
+	opt.Flags = []string{"process_top_n=9", "crawl_namespaces=0, 14"}
+
+	c, src, err := Load(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, value string
+		src        Source
+	}{
+		{"default_ext", ".txt", Source{Layer: LayerDefault}},
+		{"batch_workers", "2", Source{LayerSystem, sys, 2}},
+		{"out_dir", filepath.Join(opt.SystemDir, "sortie"), Source{LayerSystem, sys, 3}},
+		{"watch_interval", "1m0s", Source{LayerUser, usr, 3}},
+		{"batch_exts", ".log,.md", Source{LayerUser, usr, 4}},
+		{"wiki_lang", "es", Source{LayerProject, prj, 2}},
+		{"process_top_n", "9", Source{LayerFlag, "process_top_n", 0}},
+		{"crawl_namespaces", "0,14", Source{LayerFlag, "crawl_namespaces", 0}},
+	}
+	for _, tt := range tests {
+		if v, _ := c.Get(tt.key); v != tt.value {
+			t.Errorf("%s = %q, veut %q", tt.key, v, tt.value)
+		}
+		if src[tt.key] != tt.src {
+			t.Errorf("source de %s : %v, veut %v", tt.key, src[tt.key], tt.src)
+		}
+	}
+
+	// sans option, la variable d'environnement l'emporte sur le projet
+	opt.Flags = nil
+	c, src, err = Load(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ProcessTopN != 7 || src["process_top_n"].String() != "env FILEOPS_PROCESS_TOP_N" {
+		t.Errorf("process_top_n = %d (%s)", c.ProcessTopN, src["process_top_n"])
+	}
+}
+
+func TestLoadPath(t *testing.T) {
+	opt := dirs(t)
+	writeFile(t, filepath.Join(opt.ProjectDir, "config.txt"), "wiki_lang=es\n")
+	other := filepath.Join(t.TempDir(), "autre.conf")
+	writeFile(t, other, "wiki_cache_max_age=2h\n")
+
+	// --config remplace la couche projet
+	opt.Path = other
+	c, src, err := Load(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.WikiLang != "fr" || c.WikiCacheMaxAge != 2*time.Hour || src["wiki_cache_max_age"].Layer != LayerProject {
+		t.Errorf("wiki_lang %q, wiki_cache_max_age %s (%s)", c.WikiLang, c.WikiCacheMaxAge, src["wiki_cache_max_age"])
+	}
+
+	opt.Path = filepath.Join(t.TempDir(), "absent.json")
+	if _, _, err := Load(opt); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("--config absent : err = %v", err)
+	}
+}
+
+func TestLineError(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		line                int
+		msg                 string
+	}{
+		{"clé inconnue", "config.txt", "wiki_lang=fr\n\nlangue=fr\n", 3, "clé inconnue « langue »"},
+		{"sans égal", "config.txt", "# x\nwiki_lang\n", 2, "« clé=valeur » attendu"},
+		{"doublon", "config.txt", "wiki_lang=fr\nwiki_lang=en\n", 2, "clé « wiki_lang » déjà définie ligne 1"},
+		{"entier", "config.txt", "batch_workers=quatre\n", 1, "batch_workers : entier attendu"},
+		{"durée", "config.txt", "watch_interval=10\n", 1, "durée invalide"},
+		{"JSON clé inconnue", "config.json", "{\n  \"wiki_lang\": \"fr\",\n  \"langue\": 1\n}", 3, "clé inconnue"},
+		{"JSON type", "config.json", "{\n\n  \"batch_workers\": true\n}", 3, "int attendu"},
+		{"JSON chaîne", "config.json", "{\"wiki_lang\": 3}", 1, "chaîne attendue"},
+		{"JSON syntaxe", "config.json", "{\n  \"wiki_lang\": \"fr\"\n  \"out_dir\": \"x\"\n}", 3, "JSON invalide"},
+		{"JSON tableau", "config.json", "[]", 1, "objet JSON attendu"},
+		{"JSON après l'objet", "config.json", "{}\n{}", 2, "contenu après l'objet JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := dirs(t)
+			path := filepath.Join(opt.ProjectDir, tt.file)
+			writeFile(t, path, tt.content)
+			_, _, err := Load(opt)
+			var le *LineError
+			if !errors.As(err, &le) {
+				t.Fatalf("err = %v, veut LineError", err)
+			}
+			if le.File != path || le.Line != tt.line || !strings.Contains(le.Err.Error(), tt.msg) {
+				t.Errorf("%s:%d %q, veut ligne %d %q", le.File, le.Line, le.Err, tt.line, tt.msg)
+			}
+			if !strings.HasPrefix(err.Error(), path+":") {
+				t.Errorf("Error() = %q", err)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   []string
+		flags []string
+		msg   string
+	}{
+		{"option sans égal", nil, []string{"wiki_lang"}, "clé=valeur attendu"},
+		{"option inconnue", nil, []string{"langue=fr"}, "clé inconnue « langue »"},
+		{"option invalide", nil, []string{"wiki_cache=oui"}, "option wiki_cache : booléen attendu"},
+		{"variable invalide", []string{"FILEOPS_BATCH_WORKERS=x"}, nil, "variable FILEOPS_BATCH_WORKERS : entier attendu"},
+		// la validation situe l'erreur dans sa source
+		{"validation", []string{"FILEOPS_BATCH_WORKERS=0"}, nil, "batch_workers (env FILEOPS_BATCH_WORKERS) : entre 1 et 64"},
+		{"validation option", nil, []string{"disk_warn_pct=4"}, "disk_crit_pct (défaut) : entre 0 et disk_warn_pct (4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := dirs(t)
+			opt.Env, opt.Flags = append([]string{}, tt.env...), tt.flags
+			_, _, err := Load(opt)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("err = %v, veut %q", err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Action est une opération sur le cycle de vie d'un conteneur.
+type Action string
+
+const (
+	ActStart   Action = "start"
+	ActStop    Action = "stop"
+	ActRestart Action = "restart"
+	ActPause   Action = "pause"
+	ActUnpause Action = "unpause"
+	ActRemove  Action = "rm"
+)
+
+// Actions liste les actions dans l'ordre d'affichage.
+var Actions = []Action{ActStart, ActStop, ActRestart, ActPause, ActUnpause, ActRemove}
+
+// DefaultStopTimeout est le délai de grâce avant SIGKILL, comme docker stop.
+const DefaultStopTimeout = 10 * time.Second
+
+// ParseAction lit un nom d'action (remove accepté pour rm).
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(strings.TrimSpace(s))); a {
+	case "remove":
+		return ActRemove, nil
+	case ActStart, ActStop, ActRestart, ActPause, ActUnpause, ActRemove:
+		return a, nil
+	}
+	return "", fmt.Errorf("action inconnue %q (start, stop, restart, pause, unpause, rm)", s)
+}
+
+// ActionOptions règle stop, restart (Timeout) et rm (Force).
+type ActionOptions struct {
+	Timeout time.Duration // délai avant SIGKILL (0 = DefaultStopTimeout)
+	Force   bool          // rm d'un conteneur en cours
+}
+
+// ContainerInfo est le résultat de inspect, réduit à l'essentiel.
+type ContainerInfo struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	Command      string            `json:"command"`
+	Created      time.Time         `json:"created"`
+	Status       string            `json:"status"` // running, paused, exited…
+	PID          int               `json:"pid"`
+	ExitCode     int               `json:"exit_code"`
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   time.Time         `json:"finished_at"`
+	RestartCount int               `json:"restart_count"`
+	Ports        []string          `json:"ports"`
+	Mounts       []string          `json:"mounts"`
+	Networks     map[string]string `json:"networks"` // réseau → adresse IP
+}
+
+// Label décrit le conteneur pour les confirmations et l'audit.
+func (c ContainerInfo) Label() string {
+	return fmt.Sprintf("id=%s name=%s image=%s", c.ID, c.Name, c.Image)
+}
+
+// réponse de GET /containers/{id}/json et de docker inspect
+type apiInspect struct {
+	ID      string    `json:"Id"`
+	Name    string    `json:"Name"`
+	Created time.Time `json:"Created"`
+	Path    string    `json:"Path"`
+	Args    []string  `json:"Args"`
+	State   struct {
+		Status     string    `json:"Status"`
+		Pid        int       `json:"Pid"`
+		ExitCode   int       `json:"ExitCode"`
+		StartedAt  time.Time `json:"StartedAt"`
+		FinishedAt time.Time `json:"FinishedAt"`
+	} `json:"State"`
+	RestartCount int `json:"RestartCount"`
+	Config       struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	Mounts []struct {
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func (r apiInspect) info() ContainerInfo {
+	c := ContainerInfo{
+		ID:           r.ID,
+		Name:         strings.TrimPrefix(r.Name, "/"),
+		Image:        r.Config.Image,
+		Command:      strings.TrimSpace(r.Path + " " + strings.Join(r.Args, " ")),
+		Created:      r.Created,
+		Status:       r.State.Status,
+		PID:          r.State.Pid,
+		ExitCode:     r.State.ExitCode,
+		StartedAt:    r.State.StartedAt,
+		FinishedAt:   r.State.FinishedAt,
+		RestartCount: r.RestartCount,
+		Networks:     map[string]string{},
+	}
+	for port, binds := range r.NetworkSettings.Ports {
+		if len(binds) == 0 {
+			c.Ports = append(c.Ports, port)
+		}
+		for _, b := range binds {
+			c.Ports = append(c.Ports, b.HostIP+":"+b.HostPort+"->"+port)
+		}
+	}
+	sort.Strings(c.Ports)
+	for _, m := range r.Mounts {
+		mode := "ro"
+		if m.RW {
+			mode = "rw"
+		}
+		c.Mounts = append(c.Mounts, m.Source+":"+m.Destination+":"+mode)
+	}
+	for name, n := range r.NetworkSettings.Networks {
+		c.Networks[name] = n.IPAddress
+	}
+	return c
+}
+
+// Inspect renvoie les détails du conteneur id (ID ou nom).
+func (e *Engine) Inspect(ctx context.Context, id string) (ContainerInfo, error) {
+	var r apiInspect
+	if err := e.get(ctx, "/containers/"+url.PathEscape(id)+"/json", &r); err != nil {
+		return ContainerInfo{}, err
+	}
+	return r.info(), nil
+}
+
+// Do applique act au conteneur id. Le démon attend jusqu'à Timeout que
+// le conteneur s'arrête : le délai HTTP est allongé d'autant.
+func (e *Engine) Do(ctx context.Context, act Action, id string, opt ActionOptions) error {
+	path := "/containers/" + url.PathEscape(id)
+	method := http.MethodPost
+	switch act {
+	case ActStart, ActPause, ActUnpause:
+		path += "/" + string(act)
+	case ActStop, ActRestart:
+		path += "/" + string(act) + "?t=" + strconv.Itoa(stopSeconds(opt))
+		c := *e.HTTP
+		c.Timeout += stopTimeout(opt)
+		e = &Engine{Host: e.Host, HTTP: &c, base: e.base}
+	case ActRemove:
+		method = http.MethodDelete
+		if opt.Force {
+			path += "?force=true"
+		}
+	default:
+		return fmt.Errorf("action inconnue %q", act)
+	}
+	// 304 (déjà démarré, déjà arrêté) n'est pas une erreur
+	return e.do(ctx, method, path, nil)
+}
+
+func stopTimeout(opt ActionOptions) time.Duration {
+	if opt.Timeout <= 0 {
+		return DefaultStopTimeout
+	}
+	return opt.Timeout
+}
+
+// stopSeconds arrondit le délai à la seconde supérieure (au moins 1) :
+// Docker compte en secondes, et 0 tuerait le conteneur sur-le-champ.
+func stopSeconds(opt ActionOptions) int {
+	return max(int(math.Ceil(stopTimeout(opt).Seconds())), 1)
+}
+
+// Inspect renvoie les détails d'un conteneur.
+func (d *Docker) Inspect(id string) (ContainerInfo, error) {
+	if d.Engine != nil {
+		c, err := d.Engine.Inspect(context.Background(), id)
+		if err == nil || !d.fallback(err) {
+			return c, err
+		}
+		c, cerr := cliInspect(id)
+		if cerr != nil {
+			return c, err
+		}
+		return c, nil
+	}
+	return cliInspect(id)
+}
+
+// Do applique act au conteneur id, par l'API ou la commande docker.
+func (d *Docker) Do(act Action, id string, opt ActionOptions) error {
+	if d.Engine != nil {
+		err := d.Engine.Do(context.Background(), act, id, opt)
+		if err == nil || !d.fallback(err) {
+			return err
+		}
+		if cliDo(act, id, opt) != nil {
+			return err
+		}
+		return nil
+	}
+	return cliDo(act, id, opt)
+}
+
+func cliInspect(id string) (ContainerInfo, error) {
+	out, err := exec.Command("docker", "inspect", "--type", "container", id).Output()
+	if err != nil {
+		return ContainerInfo{}, cliError(err)
+	}
+	var raw []apiInspect
+	if err := json.Unmarshal(out, &raw); err != nil || len(raw) != 1 {
+		return ContainerInfo{}, fmt.Errorf("sortie docker inspect inattendue pour %s", id)
+	}
+	return raw[0].info(), nil
+}
+
+func cliDo(act Action, id string, opt ActionOptions) error {
+	args := []string{string(act)}
+	switch act {
+	case ActStop, ActRestart:
+		args = append(args, "-t", strconv.Itoa(stopSeconds(opt)))
+	case ActRemove:
+		if opt.Force {
+			args = append(args, "-f")
+		}
+	}
+	args = append(args, id)
+	if _, err := exec.Command("docker", args...).Output(); err != nil {
+		return cliError(err)
+	}
+	return nil
+}
@@ -2,24 +2,179 @@ package infra
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Container struct {
-	ID     string
-	Name   string
-	Image  string
-	Status string
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	Status string `json:"status"`
 }
 
+// ErrNoCLI : commande docker absente (repli impossible).
+var ErrNoCLI = errors.New("docker non installé ou pas dans PATH")
+
+// Backends de Docker : API seule, CLI seule, ou API avec repli sur la CLI.
+const (
+	BackendAuto = "auto"
+	BackendAPI  = "api"
+	BackendCLI  = "cli"
+)
+
+// Docker choisit entre l'API Engine et la commande docker.
+type Docker struct {
+	Engine  *Engine // nil : CLI seule
+	Backend string
+}
+
+// NewDocker crée un client pour host (voir NewEngine) et backend.
+func NewDocker(host, backend string) (*Docker, error) {
+	d := &Docker{Backend: backend}
+	if backend == "" {
+		d.Backend = BackendAuto
+	}
+	switch d.Backend {
+	case BackendAuto, BackendAPI:
+		e, err := NewEngine(host)
+		if err != nil {
+			return nil, err
+		}
+		d.Engine = e
+	case BackendCLI:
+	default:
+		return nil, fmt.Errorf("backend Docker inconnu %q (auto, api, cli)", backend)
+	}
+	return d, nil
+}
+
+// fallback indique si l'échec de l'API autorise le repli sur la CLI : le
+// démon n'est pas joignable par le socket (une erreur du démon lui-même
+// serait la même avec la CLI).
+func (d *Docker) fallback(err error) bool {
+	var de *DaemonError
+	return d.Backend == BackendAuto && !errors.As(err, &de)
+}
+
+// List renvoie les conteneurs en cours d'exécution.
+func (d *Docker) List() ([]Container, error) {
+	if d.Engine != nil {
+		cs, err := d.Engine.List(context.Background())
+		if err == nil || !d.fallback(err) {
+			return cs, err
+		}
+		cs, cerr := cliList()
+		if cerr != nil {
+			return nil, err
+		}
+		return cs, nil
+	}
+	return cliList()
+}
+
+// Stats renvoie un relevé des ressources d'un conteneur.
+func (d *Docker) Stats(containerID string) (ContainerStats, error) {
+	if d.Engine != nil {
+		st, err := d.Engine.Stats(context.Background(), containerID)
+		if err == nil || !d.fallback(err) {
+			return st, err
+		}
+		st, cerr := cliStats(containerID)
+		if cerr != nil {
+			return st, err
+		}
+		return st, nil
+	}
+	return cliStats(containerID)
+}
+
+// StatsAll relève tous les conteneurs en cours, triés par by (cpu, mem).
+func (d *Docker) StatsAll(by string) ([]ContainerStats, error) {
+	list, err := d.statsAll()
+	if err != nil {
+		return nil, err
+	}
+	return list, SortStats(list, by)
+}
+
+func (d *Docker) statsAll() ([]ContainerStats, error) {
+	if d.Engine == nil {
+		return cliStatsAll()
+	}
+	list, err := d.Engine.StatsAll(context.Background())
+	if err == nil || !d.fallback(err) {
+		return list, err
+	}
+	list, cerr := cliStatsAll()
+	if cerr != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Sample relève n fois tous les conteneurs (ou ids) à interval d'écart
+// et résume chacun par min/moy/max. progress (facultatif) est appelé
+// après chaque relevé.
+func (d *Docker) Sample(ids []string, n int, interval time.Duration, progress func(i int)) ([]StatsSummary, error) {
+	var rounds [][]ContainerStats
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		var round []ContainerStats
+		if len(ids) == 0 {
+			list, err := d.statsAll()
+			if err != nil {
+				return nil, err
+			}
+			round = list
+		} else {
+			for _, id := range ids {
+				s, err := d.Stats(id)
+				if err != nil {
+					return nil, err
+				}
+				round = append(round, s)
+			}
+		}
+		rounds = append(rounds, round)
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+	return Summarize(rounds), nil
+}
+
+// List interroge le démon par défaut (DOCKER_HOST), avec repli sur la CLI.
 func List() ([]Container, error) {
-	cmd := exec.Command("docker", "ps", "--format", "{{json .}}")
+	d, err := NewDocker("", BackendAuto)
+	if err != nil {
+		return nil, err
+	}
+	return d.List()
+}
+
+// Stats interroge le démon par défaut, avec repli sur la CLI.
+func Stats(containerID string) (ContainerStats, error) {
+	d, err := NewDocker("", BackendAuto)
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	return d.Stats(containerID)
+}
+
+func cliList() ([]Container, error) {
+	cmd := exec.Command("docker", "ps", "--no-trunc", "--format", "{{json .}}")
 	out, err := cmd.Output()
 	if err != nil {
-		return nil, errors.New("docker non installé ou pas dans PATH")
+		return nil, cliError(err)
 	}
 	var res []Container
 	for _, line := range bytes.Split(out, []byte{'\n'}) {
@@ -40,12 +195,54 @@ func List() ([]Container, error) {
 	return res, nil
 }
 
-// Stats renvoie une ligne « CPU %   MEM % »
-func Stats(containerID string) (string, error) {
-	out, err := exec.Command("docker", "stats", "--no-stream",
-		"--format", "{{.CPUPerc}} {{.MemPerc}}", containerID).Output()
+func cliStats(containerID string) (ContainerStats, error) {
+	list, err := cliStatsRun(containerID)
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	if len(list) != 1 {
+		return ContainerStats{}, fmt.Errorf("docker stats : %d relevés pour %s", len(list), containerID)
+	}
+	return list[0], nil
+}
+
+func cliStatsAll() ([]ContainerStats, error) {
+	return cliStatsRun()
+}
+
+func cliStatsRun(ids ...string) ([]ContainerStats, error) {
+	args := append([]string{"stats", "--no-stream", "--no-trunc", "--format", "{{json .}}"}, ids...)
+	out, err := exec.Command("docker", args...).Output()
 	if err != nil {
-		return "", err
+		return nil, cliError(err)
+	}
+	var res []ContainerStats
+	for _, line := range bytes.Split(out, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		s, err := parseCLIStats(line)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// cliError distingue la commande absente d'un échec de docker (stderr).
+func cliError(err error) error {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+		return fmt.Errorf("docker : %s", strings.TrimSpace(string(ee.Stderr)))
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrNoCLI
 	}
-	return strings.TrimSpace(string(out)), nil
+	return fmt.Errorf("docker : %w", err)
+}
+
+// parsePercent lit une valeur « 12.34% ».
+func parsePercent(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
 }
@@ -0,0 +1,314 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultDockerHost est le socket du démon Docker sous Linux et macOS.
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// Erreurs de connexion au démon, à tester avec errors.Is.
+var (
+	ErrSocketMissing = errors.New("socket Docker introuvable (démon absent ou DOCKER_HOST erroné)")
+	ErrPermission    = errors.New("accès au socket Docker refusé (groupe docker ?)")
+	ErrDaemonDown    = errors.New("le démon Docker ne répond pas")
+)
+
+// DaemonError est une réponse d'erreur de l'API Docker.
+type DaemonError struct {
+	Status  int
+	Message string
+}
+
+func (e *DaemonError) Error() string {
+	return fmt.Sprintf("démon Docker : %s (HTTP %d)", e.Message, e.Status)
+}
+
+// Engine parle à l'API Docker Engine en HTTP, sur socket unix ou TCP.
+type Engine struct {
+	Host string
+	HTTP *http.Client
+	base string
+}
+
+// DockerHost renvoie host, sinon DOCKER_HOST, sinon DefaultDockerHost.
+func DockerHost(host string) string {
+	if host != "" {
+		return host
+	}
+	if h := os.Getenv("DOCKER_HOST"); h != "" {
+		return h
+	}
+	return DefaultDockerHost
+}
+
+// NewEngine crée un client pour host (unix:///chemin ou tcp://hôte:port ;
+// vide = DOCKER_HOST ou le socket par défaut).
+func NewEngine(host string) (*Engine, error) {
+	host = DockerHost(host)
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("DOCKER_HOST invalide %q : %w", host, err)
+	}
+	e := &Engine{Host: host}
+	tr := &http.Transport{}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		// l'hôte est ignoré sur un socket unix
+		e.base = "http://docker"
+	case "tcp", "http":
+		e.base = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("DOCKER_HOST %q : schéma %s non géré (unix:// ou tcp://)", host, u.Scheme)
+	}
+	e.HTTP = &http.Client{Transport: tr, Timeout: 30 * time.Second}
+	return e, nil
+}
+
+// get décode la réponse JSON de GET path dans v.
+func (e *Engine) get(ctx context.Context, path string, v any) error {
+	return e.do(ctx, http.MethodGet, path, v)
+}
+
+// do envoie une requête sans corps et décode la réponse (v nil : ignorée).
+func (e *Engine) do(ctx context.Context, method, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, e.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return e.connError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(b, &msg) != nil || msg.Message == "" {
+			msg.Message = strings.TrimSpace(string(b))
+		}
+		return &DaemonError{Status: resp.StatusCode, Message: msg.Message}
+	}
+	if v == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("réponse Docker illisible : %w", err)
+	}
+	return nil
+}
+
+// connError classe une erreur de connexion en ErrSocketMissing,
+// ErrPermission ou ErrDaemonDown.
+func (e *Engine) connError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, syscall.ENOENT):
+		return fmt.Errorf("%w : %s", ErrSocketMissing, e.Host)
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("%w : %s", ErrPermission, e.Host)
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w : %s (%v)", ErrDaemonDown, e.Host, err)
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return fmt.Errorf("%w : %s (%v)", ErrDaemonDown, e.Host, err)
+	}
+	return fmt.Errorf("docker %s : %w", e.Host, err)
+}
+
+// conteneur tel que renvoyé par GET /containers/json
+type apiContainer struct {
+	ID     string   `json:"Id"`
+	Names  []string `json:"Names"`
+	Image  string   `json:"Image"`
+	Status string   `json:"Status"`
+}
+
+// List renvoie les conteneurs en cours d'exécution.
+func (e *Engine) List(ctx context.Context) ([]Container, error) {
+	var raw []apiContainer
+	if err := e.get(ctx, "/containers/json", &raw); err != nil {
+		return nil, err
+	}
+	res := make([]Container, 0, len(raw))
+	for _, c := range raw {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		res = append(res, Container{ID: c.ID, Name: name, Image: c.Image, Status: c.Status})
+	}
+	return res, nil
+}
+
+// statistiques brutes de GET /containers/{id}/stats
+type apiStats struct {
+	CPUStats    apiCPU `json:"cpu_stats"`
+	PreCPUStats apiCPU `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	BlkioStats struct {
+		IOServiceBytes []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+	Name string    `json:"name"`
+	Read time.Time `json:"read"`
+}
+
+type apiCPU struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  int    `json:"online_cpus"`
+}
+
+// Stats lit un échantillon de statistiques du conteneur id. Le démon
+// attend un second relevé pour calculer l'écart CPU (environ 1 s).
+func (e *Engine) Stats(ctx context.Context, id string) (ContainerStats, error) {
+	var s apiStats
+	if err := e.get(ctx, "/containers/"+url.PathEscape(id)+"/stats?stream=false", &s); err != nil {
+		return ContainerStats{}, err
+	}
+	return s.typed(id), nil
+}
+
+// StatsConcurrency borne les relevés simultanés de StatsAll.
+const StatsConcurrency = 8
+
+// StatsAll relève tous les conteneurs en cours, StatsConcurrency à la fois
+// (chaque relevé prend environ une seconde côté démon).
+func (e *Engine) StatsAll(ctx context.Context) ([]ContainerStats, error) {
+	cs, err := e.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ContainerStats, len(cs))
+	errs := make([]error, len(cs))
+	sem := make(chan struct{}, StatsConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range cs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res[i], errs[i] = e.Stats(ctx, c.ID)
+			res[i].Name = c.Name
+		}()
+	}
+	wg.Wait()
+
+	out := res[:0]
+	for i, err := range errs {
+		var de *DaemonError
+		switch {
+		case errors.As(err, &de) && de.Status == http.StatusNotFound:
+			// arrêté entre la liste et le relevé
+		case err != nil:
+			return nil, err
+		default:
+			out = append(out, res[i])
+		}
+	}
+	return out, nil
+}
+
+func (s apiStats) typed(id string) ContainerStats {
+	st := ContainerStats{
+		ID:         id,
+		Name:       strings.TrimPrefix(s.Name, "/"),
+		Time:       s.Read,
+		CPUPercent: s.cpuPercent(),
+		MemUsage:   s.memUsed(),
+		MemLimit:   s.MemoryStats.Limit,
+		MemPercent: s.memPercent(),
+		PIDs:       s.PidsStats.Current,
+	}
+	if st.Time.IsZero() {
+		st.Time = time.Now()
+	}
+	for _, n := range s.Networks {
+		st.NetRx += n.RxBytes
+		st.NetTx += n.TxBytes
+	}
+	for _, b := range s.BlkioStats.IOServiceBytes {
+		switch strings.ToLower(b.Op) {
+		case "read":
+			st.BlockRead += b.Value
+		case "write":
+			st.BlockWrite += b.Value
+		}
+	}
+	return st
+}
+
+// cpuPercent suit le calcul de docker stats : part du temps CPU système
+// consommée entre les deux relevés, multipliée par le nombre de CPU.
+func (s apiStats) cpuPercent() float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || sysDelta <= 0 {
+		return 0
+	}
+	cpus := s.CPUStats.OnlineCPUs
+	if cpus == 0 {
+		cpus = max(len(s.CPUStats.CPUUsage.PercpuUsage), 1)
+	}
+	return cpuDelta / sysDelta * float64(cpus) * 100
+}
+
+// memUsed retire le cache de page de l'utilisation (cgroup v1 : cache,
+// v2 : inactive_file), comme docker stats.
+func (s apiStats) memUsed() uint64 {
+	used := s.MemoryStats.Usage
+	cache := s.MemoryStats.Stats["inactive_file"]
+	if c, ok := s.MemoryStats.Stats["cache"]; ok && cache == 0 {
+		cache = c
+	}
+	if cache < used {
+		used -= cache
+	}
+	return used
+}
+
+func (s apiStats) memPercent() float64 {
+	if s.MemoryStats.Limit == 0 {
+		return 0
+	}
+	return float64(s.memUsed()) / float64(s.MemoryStats.Limit) * 100
+}
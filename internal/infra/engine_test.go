@@ -0,0 +1,226 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDaemon sert handler sur un socket unix temporaire et renvoie un
+// Engine qui s'y connecte.
+func fakeDaemon(t *testing.T, handler http.Handler) *Engine {
+	t.Helper()
+	// pas t.TempDir() : un chemin de socket dépasse vite 108 octets
+	dir, err := os.MkdirTemp("", "dock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	e, err := NewEngine("unix://" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+const statsBody = `{"name":"/%s","read":"2026-10-18T10:00:00Z",
+"cpu_stats":{"cpu_usage":{"total_usage":2000000000},"system_cpu_usage":20000000000,"online_cpus":4},
+"precpu_stats":{"cpu_usage":{"total_usage":1000000000},"system_cpu_usage":10000000000},
+"memory_stats":{"usage":300000000,"limit":2000000000,"stats":{"inactive_file":100000000}},
+"networks":{"eth0":{"rx_bytes":1500,"tx_bytes":900},"eth1":{"rx_bytes":500,"tx_bytes":100}},
+"blkio_stats":{"io_service_bytes_recursive":[{"op":"Read","value":4096},{"op":"Write","value":8192}]},
+"pids_stats":{"current":3}}`
+
+func TestEngineList(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Container
+	}{
+		{"vide", `[]`, []Container{}},
+		{"deux", `[{"Id":"abc","Names":["/web"],"Image":"nginx","Status":"Up 2 hours"},{"Id":"def","Names":[],"Image":"pg","Status":"Up"}]`,
+			[]Container{{ID: "abc", Name: "web", Image: "nginx", Status: "Up 2 hours"}, {ID: "def", Image: "pg", Status: "Up"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/containers/json" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			got, err := e.List(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("List() = %+v, veut %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineStats(t *testing.T) {
+	e := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "false" {
+			t.Errorf("stream = %q", r.URL.Query().Get("stream"))
+		}
+		fmt.Fprintf(w, statsBody, "web")
+	}))
+	got, err := e.Stats(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ContainerStats{
+		ID: "abc", Name: "web", Time: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+		CPUPercent: 40, MemUsage: 200000000, MemLimit: 2000000000, MemPercent: 10,
+		NetRx: 2000, NetTx: 1000, BlockRead: 4096, BlockWrite: 8192, PIDs: 3,
+	}
+	if math.Abs(got.CPUPercent-want.CPUPercent) > 1e-9 || math.Abs(got.MemPercent-want.MemPercent) > 1e-9 {
+		t.Errorf("CPU %v %%, mémoire %v %% ; veut %v, %v", got.CPUPercent, got.MemPercent, want.CPUPercent, want.MemPercent)
+	}
+	got.CPUPercent, got.MemPercent = want.CPUPercent, want.MemPercent
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("Time = %v, veut %v", got.Time, want.Time)
+	}
+	got.Time = want.Time
+	if got != want {
+		t.Errorf("Stats() =\n %+v\nveut\n %+v", got, want)
+	}
+}
+
+func TestEngineStatsAll(t *testing.T) {
+	const n = 3*StatsConcurrency + 1
+	var running, peak atomic.Int32
+	e := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/json" {
+			var list []string
+			for i := range n {
+				list = append(list, fmt.Sprintf(`{"Id":"c%d","Names":["/n%d"]}`, i, i))
+			}
+			fmt.Fprint(w, "["+strings.Join(list, ",")+"]")
+			return
+		}
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")[0]
+		if id == "c5" {
+			// arrêté entre la liste et le relevé
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such container: c5"}`)
+			return
+		}
+		fmt.Fprintf(w, statsBody, id)
+	}))
+
+	got, err := e.StatsAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != n-1 {
+		t.Errorf("%d relevés, veut %d", len(got), n-1)
+	}
+	for _, s := range got {
+		if s.ID == "c5" || s.Name != "n"+strings.TrimPrefix(s.ID, "c") {
+			t.Errorf("relevé %s nommé %q", s.ID, s.Name)
+		}
+	}
+	if p := peak.Load(); p > StatsConcurrency {
+		t.Errorf("%d relevés simultanés, limite %d", p, StatsConcurrency)
+	}
+}
+
+func TestEngineDo(t *testing.T) {
+	tests := []struct {
+		act     Action
+		opt     ActionOptions
+		method  string
+		uri     string
+		status  int
+		wantErr bool
+	}{
+		{ActStart, ActionOptions{}, "POST", "/containers/web/start", 204, false},
+		{ActStart, ActionOptions{}, "POST", "/containers/web/start", 304, false},
+		{ActStop, ActionOptions{}, "POST", "/containers/web/stop?t=10", 204, false},
+		{ActStop, ActionOptions{Timeout: 500 * time.Millisecond}, "POST", "/containers/web/stop?t=1", 204, false},
+		{ActRestart, ActionOptions{Timeout: 2500 * time.Millisecond}, "POST", "/containers/web/restart?t=3", 204, false},
+		{ActPause, ActionOptions{}, "POST", "/containers/web/pause", 409, true},
+		{ActUnpause, ActionOptions{}, "POST", "/containers/web/unpause", 204, false},
+		{ActRemove, ActionOptions{}, "DELETE", "/containers/web", 204, false},
+		{ActRemove, ActionOptions{Force: true}, "DELETE", "/containers/web?force=true", 204, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s %d", tt.act, tt.opt.Timeout, tt.status), func(t *testing.T) {
+			var mu sync.Mutex
+			var method, uri string
+			e := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				method, uri = r.Method, r.URL.RequestURI()
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+				if tt.status >= 400 {
+					fmt.Fprint(w, `{"message":"container is not running"}`)
+				}
+			}))
+			err := e.Do(context.Background(), tt.act, "web", tt.opt)
+			mu.Lock()
+			defer mu.Unlock()
+			if method != tt.method || uri != tt.uri {
+				t.Errorf("requête %s %s, veut %s %s", method, uri, tt.method, tt.uri)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			var de *DaemonError
+			if !errors.As(err, &de) || de.Status != tt.status || de.Message != "container is not running" {
+				t.Errorf("err = %v, veut DaemonError %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestEngineConnError(t *testing.T) {
+	e, err := NewEngine("unix://" + filepath.Join(t.TempDir(), "absent.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.List(context.Background()); !errors.Is(err, ErrSocketMissing) {
+		t.Errorf("socket absent : %v", err)
+	}
+	if _, err := NewEngine("ssh://hote"); err == nil {
+		t.Error("schéma ssh accepté")
+	}
+}
@@ -0,0 +1,154 @@
+package infra
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MountInfoPath est la table des montages lue par Mounts.
+var MountInfoPath = "/proc/self/mountinfo"
+
+// Mount est une ligne de mountinfo.
+type Mount struct {
+	Point    string `json:"point"`
+	Source   string `json:"source"`
+	FSType   string `json:"fs_type"`
+	Dev      string `json:"dev"` // majeur:mineur
+	ReadOnly bool   `json:"read_only"`
+}
+
+// pseudoFS sont les systèmes de fichiers sans stockage réel.
+var pseudoFS = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "tmpfs": true,
+	"ramfs": true, "cgroup": true, "cgroup2": true, "mqueue": true, "debugfs": true,
+	"tracefs": true, "securityfs": true, "pstore": true, "bpf": true, "configfs": true,
+	"fusectl": true, "hugetlbfs": true, "autofs": true, "binfmt_misc": true,
+	"rpc_pipefs": true, "nsfs": true, "selinuxfs": true, "efivarfs": true,
+	"squashfs": true, "nfsd": true, "fuse.gvfsd-fuse": true, "fuse.portal": true,
+	"fuse.lxcfs": true,
+}
+
+// Mounts lit la table des montages (ordre du noyau).
+func Mounts() ([]Mount, error) {
+	f, err := os.Open(MountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []Mount
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// id parent maj:min racine point options [facultatifs...] - type source super
+		fields := strings.Fields(sc.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 6 || len(fields) < sep+3 {
+			continue
+		}
+		res = append(res, Mount{
+			Point:    unescapeMount(fields[4]),
+			Source:   unescapeMount(fields[sep+2]),
+			FSType:   fields[sep+1],
+			Dev:      fields[2],
+			ReadOnly: strings.HasPrefix(fields[5], "ro"),
+		})
+	}
+	return res, sc.Err()
+}
+
+// unescapeMount décode les octaux de mountinfo (\040 = espace).
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// RealMounts renvoie les montages sur stockage réel : sans pseudo-systèmes
+// de fichiers ni doublons d'un même périphérique (montages bind).
+func RealMounts() ([]Mount, error) {
+	all, err := Mounts()
+	if err != nil {
+		return nil, err
+	}
+	var res []Mount
+	seen := map[string]bool{}
+	for _, m := range all {
+		if pseudoFS[m.FSType] || seen[m.Dev] {
+			continue
+		}
+		seen[m.Dev] = true
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// MountOf renvoie le montage contenant path (le plus long préfixe).
+func MountOf(path string) (Mount, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Mount{}, false
+	}
+	if p, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = p
+	}
+	all, err := Mounts()
+	if err != nil {
+		return Mount{}, false
+	}
+	var best Mount
+	found := false
+	for _, m := range all {
+		if within(abs, m.Point) && (!found || len(m.Point) >= len(best.Point)) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+func within(path, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// DiskReport renvoie l'occupation de chaque montage réel, classée selon t.
+func DiskReport(t Thresholds) ([]DiskUsage, error) {
+	ms, err := RealMounts()
+	if err != nil {
+		return nil, err
+	}
+	var res []DiskUsage
+	for _, m := range ms {
+		u, err := statUsage(m.Point)
+		if err != nil {
+			// montage inaccessible (permissions, réseau absent) : ignoré
+			continue
+		}
+		u.Mount, u.Device, u.FSType = m.Point, m.Source, m.FSType
+		u.Level = t.Level(u)
+		res = append(res, u)
+	}
+	return res, nil
+}
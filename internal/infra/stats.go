@@ -0,0 +1,182 @@
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContainerStats est un relevé de ressources d'un conteneur.
+type ContainerStats struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Time       time.Time `json:"time"`
+	CPUPercent float64   `json:"cpu_percent"`
+	MemUsage   uint64    `json:"mem_usage"` // octets, hors cache de pages
+	MemLimit   uint64    `json:"mem_limit"`
+	MemPercent float64   `json:"mem_percent"`
+	NetRx      uint64    `json:"net_rx"` // octets reçus, toutes interfaces
+	NetTx      uint64    `json:"net_tx"`
+	BlockRead  uint64    `json:"block_read"`
+	BlockWrite uint64    `json:"block_write"`
+	PIDs       uint64    `json:"pids"`
+}
+
+// Clés de tri des statistiques.
+const (
+	SortCPU = "cpu"
+	SortMem = "mem"
+)
+
+// SortStats trie list par CPU ou mémoire décroissants (puis par nom).
+func SortStats(list []ContainerStats, by string) error {
+	var key func(s ContainerStats) float64
+	switch by {
+	case SortCPU, "":
+		key = func(s ContainerStats) float64 { return s.CPUPercent }
+	case SortMem:
+		key = func(s ContainerStats) float64 { return float64(s.MemUsage) }
+	default:
+		return fmt.Errorf("tri inconnu %q (cpu ou mem)", by)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if ki, kj := key(list[i]), key(list[j]); ki != kj {
+			return ki > kj
+		}
+		return list[i].Name < list[j].Name
+	})
+	return nil
+}
+
+// Range résume une série de valeurs.
+type Range struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%.2f/%.2f/%.2f", r.Min, r.Avg, r.Max)
+}
+
+// StatsSummary agrège les relevés d'un conteneur (mode échantillonnage).
+type StatsSummary struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Samples    int    `json:"samples"`
+	CPUPercent Range  `json:"cpu_percent"`
+	MemUsage   Range  `json:"mem_usage"`
+	MemPercent Range  `json:"mem_percent"`
+	PIDs       Range  `json:"pids"`
+}
+
+// Summarize calcule min/moy/max par conteneur, dans l'ordre d'apparition.
+func Summarize(samples [][]ContainerStats) []StatsSummary {
+	var (
+		out   []StatsSummary
+		index = map[string]int{}
+	)
+	add := func(r *Range, n int, v float64) {
+		if n == 0 {
+			*r = Range{Min: v, Avg: v, Max: v}
+			return
+		}
+		r.Min, r.Max = min(r.Min, v), max(r.Max, v)
+		r.Avg += (v - r.Avg) / float64(n+1)
+	}
+	for _, round := range samples {
+		for _, s := range round {
+			i, ok := index[s.ID]
+			if !ok {
+				i = len(out)
+				index[s.ID] = i
+				out = append(out, StatsSummary{ID: s.ID, Name: s.Name})
+			}
+			sum := &out[i]
+			add(&sum.CPUPercent, sum.Samples, s.CPUPercent)
+			add(&sum.MemUsage, sum.Samples, float64(s.MemUsage))
+			add(&sum.MemPercent, sum.Samples, s.MemPercent)
+			add(&sum.PIDs, sum.Samples, float64(s.PIDs))
+			sum.Samples++
+		}
+	}
+	return out
+}
+
+// relevé de « docker stats --format {{json .}} »
+type cliStatsLine struct {
+	ID       string `json:"ID"`
+	Name     string `json:"Name"`
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	MemPerc  string `json:"MemPerc"`
+	NetIO    string `json:"NetIO"`
+	BlockIO  string `json:"BlockIO"`
+	PIDs     string `json:"PIDs"`
+}
+
+// parseCLIStats convertit une ligne JSON de docker stats.
+func parseCLIStats(line []byte) (ContainerStats, error) {
+	var l cliStatsLine
+	if err := json.Unmarshal(line, &l); err != nil {
+		return ContainerStats{}, fmt.Errorf("sortie docker stats inattendue : %q", line)
+	}
+	s := ContainerStats{ID: l.ID, Name: l.Name, Time: time.Now()}
+	var err error
+	if s.CPUPercent, err = parsePercent(l.CPUPerc); err != nil {
+		return s, err
+	}
+	if s.MemPercent, err = parsePercent(l.MemPerc); err != nil {
+		return s, err
+	}
+	if s.MemUsage, s.MemLimit, err = parsePair(l.MemUsage); err != nil {
+		return s, err
+	}
+	if s.NetRx, s.NetTx, err = parsePair(l.NetIO); err != nil {
+		return s, err
+	}
+	if s.BlockRead, s.BlockWrite, err = parsePair(l.BlockIO); err != nil {
+		return s, err
+	}
+	s.PIDs, _ = strconv.ParseUint(strings.TrimSpace(l.PIDs), 10, 64)
+	return s, nil
+}
+
+// parsePair lit « 1.5MiB / 2GiB ».
+func parsePair(s string) (uint64, uint64, error) {
+	a, b, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("valeur docker inattendue : %q", s)
+	}
+	x, err := parseSize(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := parseSize(b)
+	return x, y, err
+}
+
+// parseSize lit une taille docker : « 12.3MiB », « 4kB », « 0B ».
+func parseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("taille docker invalide : %q", s)
+	}
+	mult := map[string]float64{
+		"": 1, "B": 1,
+		"kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+		"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+	}[strings.TrimSpace(s[i:])]
+	if mult == 0 {
+		return 0, fmt.Errorf("unité docker inconnue : %q", s)
+	}
+	return uint64(n * mult), nil
+}
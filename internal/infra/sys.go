@@ -1,23 +1,109 @@
 package infra
 
 import (
+	"fmt"
 	"syscall"
 )
 
-func DiskFree() (float64, error) {
+// DiskUsage décrit l'occupation du système de fichiers contenant Path.
+type DiskUsage struct {
+	Path          string  `json:"path"`
+	Mount         string  `json:"mount"`
+	Device        string  `json:"device"`
+	FSType        string  `json:"fs_type"`
+	Total         uint64  `json:"total"`
+	Free          uint64  `json:"free"`
+	Used          uint64  `json:"used"`
+	FreePct       float64 `json:"free_pct"`
+	Inodes        uint64  `json:"inodes"` // 0 : pas d'inodes (btrfs, vfat…)
+	InodesFree    uint64  `json:"inodes_free"`
+	InodesFreePct float64 `json:"inodes_free_pct"`
+	Level         Level   `json:"level"`
+}
+
+// Usage renvoie l'occupation du système de fichiers contenant path et
+// le montage correspondant.
+func Usage(path string) (DiskUsage, error) {
+	u, err := statUsage(path)
+	if err != nil {
+		return u, err
+	}
+	if m, ok := MountOf(path); ok {
+		u.Mount, u.Device, u.FSType = m.Point, m.Source, m.FSType
+	}
+	return u, nil
+}
+
+func statUsage(path string) (DiskUsage, error) {
 	var st syscall.Statfs_t
-	if err := syscall.Statfs(".", &st); err != nil {
+	if err := syscall.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("%s : %w", path, err)
+	}
+	total := st.Blocks * uint64(st.Bsize)
+	free := st.Bavail * uint64(st.Bsize)
+	u := DiskUsage{
+		Path:          path,
+		Total:         total,
+		Free:          free,
+		Used:          total - st.Bfree*uint64(st.Bsize),
+		FreePct:       100,
+		Inodes:        st.Files,
+		InodesFree:    st.Ffree,
+		InodesFreePct: 100,
+	}
+	if total > 0 {
+		u.FreePct = float64(free) / float64(total) * 100
+	}
+	if st.Files > 0 {
+		u.InodesFreePct = float64(st.Ffree) / float64(st.Files) * 100
+	}
+	return u, nil
+}
+
+func DiskFree() (float64, error) {
+	u, err := Usage(".")
+	if err != nil {
 		return 0, err
 	}
-	total := float64(st.Blocks) * float64(st.Bsize)
-	free := float64(st.Bavail) * float64(st.Bsize)
-	return free / total * 100, nil
+	return u.FreePct, nil
+}
+
+// Level est l'état d'un disque par rapport aux seuils.
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "alerte"
+	LevelCritical Level = "critique"
+)
+
+// Thresholds : pourcentages d'espace (ou d'inodes) libres sous lesquels
+// un disque passe en alerte ou en critique.
+type Thresholds struct {
+	WarnPct float64
+	CritPct float64
+}
+
+// Level classe u selon le plus bas de l'espace et des inodes libres.
+func (t Thresholds) Level(u DiskUsage) Level {
+	free := min(u.FreePct, u.InodesFreePct)
+	switch {
+	case free < t.CritPct:
+		return LevelCritical
+	case free < t.WarnPct:
+		return LevelWarning
+	}
+	return LevelOK
 }
 
-// AlertColor renvoie rouge si pourcentage < 10 %
-func AlertColor(pct float64) string {
-	if pct < 10 {
+// AlertColor renvoie la couleur ANSI d'un niveau : rouge en critique,
+// jaune en alerte, sinon la couleur par défaut.
+func AlertColor(l Level) string {
+	switch l {
+	case LevelCritical:
 		return "\033[31m"
+	case LevelWarning:
+		return "\033[33m"
 	}
 	return "\033[0m"
 }
@@ -0,0 +1,349 @@
+package ops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Syntaxe des expressions de filtre :
+//
+//	ERROR AND (db OR cache) AND NOT healthcheck
+//	"connexion refusée" timeout        (AND implicite entre deux termes)
+//	re:/user=\d+/                      (expression régulière)
+//
+// Les opérateurs AND, OR et NOT s'écrivent en majuscules ; NOT est
+// prioritaire sur AND, lui-même prioritaire sur OR. Une expression vide
+// retient toutes les lignes.
+
+// FilterOptions règle la comparaison des mots et phrases.
+type FilterOptions struct {
+	CaseSensitive bool // respecte la casse (mots, phrases et regex)
+	WholeWord     bool // un mot ne correspond qu'entier (pas « error » dans « errors »)
+}
+
+// FilterError est une erreur de syntaxe, Pos étant la position (en
+// caractères, à partir de 1) dans l'expression.
+type FilterError struct {
+	Pos int
+	Msg string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filtre, position %d : %s", e.Pos, e.Msg)
+}
+
+// Filter est une expression de filtre compilée.
+type Filter struct {
+	expr string
+	root node
+	fold bool
+}
+
+// ParseFilter compile expr une fois pour toutes.
+func ParseFilter(expr string, opt FilterOptions) (*Filter, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{toks: toks, opt: opt, end: utf8.RuneCountInString(expr) + 1}
+	f := &Filter{expr: expr, fold: !opt.CaseSensitive}
+	if len(toks) == 0 {
+		return f, nil
+	}
+	if f.root, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("%q inattendu", t.text)}
+	}
+	return f, nil
+}
+
+// Match indique si line satisfait le filtre (nil retient tout).
+func (f *Filter) Match(line string) bool {
+	if f == nil || f.root == nil {
+		return true
+	}
+	l := subject{raw: line}
+	if f.fold {
+		l.lower = strings.ToLower(line)
+	}
+	return f.root.match(l)
+}
+
+// String renvoie l'expression d'origine.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// subject est la ligne testée, avec sa version en minuscules si besoin.
+type subject struct {
+	raw, lower string
+}
+
+type node interface {
+	match(l subject) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ inner node }
+
+func (n andNode) match(l subject) bool { return n.left.match(l) && n.right.match(l) }
+func (n orNode) match(l subject) bool  { return n.left.match(l) || n.right.match(l) }
+func (n notNode) match(l subject) bool { return !n.inner.match(l) }
+
+// termNode cherche un mot ou une phrase.
+type termNode struct {
+	text  string
+	fold  bool
+	whole bool
+}
+
+func (n termNode) match(l subject) bool {
+	s := l.raw
+	if n.fold {
+		s = l.lower
+	}
+	if !n.whole {
+		return strings.Contains(s, n.text)
+	}
+	for i := 0; ; {
+		j := strings.Index(s[i:], n.text)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(n.text)
+		if isBoundary(s, start, end) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		i = start + size
+	}
+}
+
+// isBoundary vérifie que s[start:end] n'est pas collé à une lettre ou un chiffre.
+func isBoundary(s string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(s[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(s[end:]); end < len(s) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+type regexNode struct{ re *regexp.Regexp }
+
+func (n regexNode) match(l subject) bool { return n.re.MatchString(l.raw) }
+
+// ANALYSE LEXICALE
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokPhrase
+	tokRegex
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func tokenize(expr string) ([]token, error) {
+	rs := []rune(expr)
+	var toks []token
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokOpen, "(", pos})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokClose, ")", pos})
+			i++
+		case r == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, &FilterError{Pos: pos, Msg: "guillemet fermant manquant"}
+			}
+			toks = append(toks, token{tokPhrase, b.String(), pos})
+			i = j + 1
+		case strings.HasPrefix(string(rs[i:]), "re:/"):
+			var b strings.Builder
+			j := i + 4
+			for ; j < len(rs) && rs[j] != '/'; j++ {
+				// « \/ » désigne un slash littéral, les autres échappements vont à la regex
+				if rs[j] == '\\' && j+1 < len(rs) && rs[j+1] == '/' {
+					j++
+				} else if rs[j] == '\\' && j+1 < len(rs) {
+					b.WriteRune(rs[j])
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, &FilterError{Pos: pos, Msg: "regex non terminée (« / » manquant)"}
+			}
+			toks = append(toks, token{tokRegex, b.String(), pos})
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '(' && rs[j] != ')' && rs[j] != '"' {
+				j++
+			}
+			word := string(rs[i:j])
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			toks = append(toks, token{kind, word, pos})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// ANALYSE SYNTAXIQUE (descente récursive)
+
+type filterParser struct {
+	toks []token
+	i    int
+	opt  FilterOptions
+	end  int
+}
+
+func (p *filterParser) peek() token {
+	if p.i >= len(p.toks) {
+		return token{kind: tokEOF, text: "fin", pos: p.end}
+	}
+	return p.toks[p.i]
+}
+
+func (p *filterParser) next() token {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+func (p *filterParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokPhrase, tokRegex, tokNot, tokOpen:
+			// AND implicite
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *filterParser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokOpen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokClose {
+			return nil, &FilterError{Pos: c.pos, Msg: fmt.Sprintf("« ) » attendu pour la parenthèse ouverte en position %d", t.pos)}
+		}
+		return n, nil
+	case tokWord, tokPhrase:
+		if t.text == "" {
+			return nil, &FilterError{Pos: t.pos, Msg: "phrase vide"}
+		}
+		text := t.text
+		if !p.opt.CaseSensitive {
+			text = strings.ToLower(text)
+		}
+		return termNode{text: text, fold: !p.opt.CaseSensitive, whole: p.opt.WholeWord}, nil
+	case tokRegex:
+		src := t.text
+		if !p.opt.CaseSensitive {
+			src = "(?i)" + src
+		}
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("regex invalide : %v", err)}
+		}
+		return regexNode{re}, nil
+	case tokEOF:
+		return nil, &FilterError{Pos: t.pos, Msg: "terme attendu en fin d'expression"}
+	default:
+		return nil, &FilterError{Pos: t.pos, Msg: fmt.Sprintf("terme attendu avant %q", t.text)}
+	}
+}
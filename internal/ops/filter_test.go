@@ -0,0 +1,153 @@
+package ops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		opt  FilterOptions
+		line string
+		want bool
+	}{
+		// expression vide : tout est retenu
+		{"", FilterOptions{}, "n'importe quoi", true},
+		{"   ", FilterOptions{}, "", true},
+
+		{"error", FilterOptions{}, "ERROR: disque plein", true},
+		{"error", FilterOptions{CaseSensitive: true}, "ERROR: disque plein", false},
+		{"error", FilterOptions{}, "3 errors", true},
+		{"error", FilterOptions{WholeWord: true}, "3 errors", false},
+		{"error", FilterOptions{WholeWord: true}, "errors, puis error.", true},
+		{"error", FilterOptions{WholeWord: true}, "my_error", false},
+		{"été", FilterOptions{WholeWord: true}, "l'ÉTÉ arrive", true},
+
+		// AND implicite et explicite
+		{"db timeout", FilterOptions{}, "db: timeout", true},
+		{"db timeout", FilterOptions{}, "db: ok", false},
+		{"db AND timeout", FilterOptions{}, "timeout db", true},
+
+		// NOT > AND > OR
+		{"a OR b AND c", FilterOptions{}, "a", true},
+		{"a OR b AND c", FilterOptions{}, "b", false},
+		{"(a OR b) AND c", FilterOptions{}, "a", false},
+		{"(a OR b) AND c", FilterOptions{}, "b c", true},
+		{"NOT a AND b", FilterOptions{}, "b", true},
+		{"NOT a AND b", FilterOptions{}, "a b", false},
+		{"NOT (a AND b)", FilterOptions{}, "a", true},
+		{"NOT NOT a", FilterOptions{}, "a", true},
+		{"ERROR AND (db OR cache) AND NOT healthcheck", FilterOptions{}, "error cache miss", true},
+		{"ERROR AND (db OR cache) AND NOT healthcheck", FilterOptions{}, "error cache healthcheck", false},
+
+		// opérateurs en minuscules : simples mots
+		{"and", FilterOptions{}, "rock and roll", true},
+		{"a or b", FilterOptions{}, "b", false},
+
+		// phrases
+		{`"connexion refusée"`, FilterOptions{}, "Connexion refusée par l'hôte", true},
+		{`"connexion refusée"`, FilterOptions{}, "refusée connexion", false},
+		{`"AND" x`, FilterOptions{}, "x AND y", true},
+		{`"dit \"non\""`, FilterOptions{}, `il dit "non"`, true},
+		{`"a(b)"`, FilterOptions{}, "f a(b)", true},
+
+		// regex
+		{`re:/user=\d+/`, FilterOptions{}, "login user=42", true},
+		{`re:/user=\d+/`, FilterOptions{}, "login user=bob", false},
+		{`re:/^GET/`, FilterOptions{}, "get /", true},
+		{`re:/^GET/`, FilterOptions{CaseSensitive: true}, "get /", false},
+		{`re:/a\/b/`, FilterOptions{}, "x a/b", true},
+		{`re:/x/ OR y`, FilterOptions{}, "y", true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr, tt.opt)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) : %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(tt.line); got != tt.want {
+			t.Errorf("%q %+v sur %q = %v, veut %v", tt.expr, tt.opt, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`"non fermé`, 1, "guillemet fermant manquant"},
+		{`a "b`, 3, "guillemet fermant manquant"},
+		{`re:/abc`, 1, "regex non terminée"},
+		{`re:/a(/`, 1, "regex invalide"},
+		{`(a OR b`, 8, "« ) » attendu pour la parenthèse ouverte en position 1"},
+		{`a)`, 2, `")" inattendu`},
+		{`a AND`, 6, "terme attendu en fin d'expression"},
+		{`NOT`, 4, "terme attendu en fin d'expression"},
+		{`OR a`, 1, `terme attendu avant "OR"`},
+		{`a AND OR b`, 7, `terme attendu avant "OR"`},
+		{`()`, 2, `terme attendu avant ")"`},
+		{`""`, 1, "phrase vide"},
+		// positions en caractères, pas en octets
+		{`éé AND`, 7, "terme attendu en fin d'expression"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.expr, FilterOptions{})
+		var fe *FilterError
+		if !errors.As(err, &fe) {
+			t.Errorf("ParseFilter(%q) : err = %v, veut FilterError", tt.expr, err)
+			continue
+		}
+		if fe.Pos != tt.pos || !strings.Contains(fe.Msg, tt.msg) {
+			t.Errorf("ParseFilter(%q) : position %d %q, veut %d %q", tt.expr, fe.Pos, fe.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestFilterNil(t *testing.T) {
+	var f *Filter
+	if !f.Match("x") || f.String() != "" {
+		t.Error("un filtre nil doit tout retenir")
+	}
+	f, _ = ParseFilter("a  OR b", FilterOptions{})
+	if f.String() != "a  OR b" {
+		t.Errorf("String() = %q", f.String())
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte("ERROR db\nINFO ok\nerror cache\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFilter("error", FilterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		keep bool
+		want string
+	}{
+		{true, "ERROR db\nerror cache\n"},
+		{false, "INFO ok\n"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(dir, "out", "lines.txt")
+		if err := FilterLines(in, f, out, tt.keep); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("keep=%v : %q, veut %q", tt.keep, b, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package ops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName est le manifeste de batch, dans le répertoire de sortie.
+const ManifestName = "batch_manifest.json"
+
+// manifestVersion change quand le format ou le calcul des statistiques
+// change : un manifeste d'une autre version est ignoré.
+const manifestVersion = 3
+
+// Manifest garde le résultat de l'analyse de chaque fichier déjà traité,
+// par chemin absolu, pour ne réanalyser que les fichiers nouveaux ou
+// modifiés ; les lots de plusieurs répertoires y cohabitent.
+type Manifest struct {
+	Version int                      `json:"version"`
+	Files   map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestEntry décrit un fichier analysé : Size, ModTime et SHA256 lus
+// dans la même passe que ses statistiques (sans vocabulaire, qui demande
+// de relire le fichier).
+type ManifestEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+	Info    FileInfo  `json:"info"`
+}
+
+// LoadManifest lit le manifeste path ; absent, illisible ou d'une autre
+// version, il est remplacé par un manifeste vide (tout est réanalysé).
+func LoadManifest(path string) *Manifest {
+	m := &Manifest{Version: manifestVersion, Files: map[string]ManifestEntry{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m
+	}
+	var old Manifest
+	if json.Unmarshal(b, &old) != nil || old.Version != manifestVersion || old.Files == nil {
+		return m
+	}
+	return &old
+}
+
+// Save écrit le manifeste via un fichier temporaire renommé.
+func (m *Manifest) Save(path string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// lookup renvoie l'entrée de key (chemin absolu de path) si le fichier n'a
+// pas changé : même taille et même date, ou même taille et même contenu
+// (SHA-256) après un simple changement de date.
+func (m *Manifest) lookup(key, path string, st os.FileInfo) (ManifestEntry, bool) {
+	e, ok := m.Files[key]
+	if !ok || e.Size != st.Size() {
+		return e, false
+	}
+	if !e.ModTime.Equal(st.ModTime()) {
+		sum, err := hashFile(path)
+		if err != nil || sum != e.SHA256 {
+			return e, false
+		}
+		e.ModTime, e.Info.ModTime = st.ModTime(), st.ModTime()
+	}
+	e.Info.Path = path
+	return e, true
+}
+
+// hashFile renvoie le SHA-256 de path en hexadécimal.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
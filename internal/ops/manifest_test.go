@@ -0,0 +1,196 @@
+package ops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string // "" = fichier absent
+		files   int
+	}{
+		{"absent", "", 0},
+		{"illisible", "{", 0},
+		{"ancienne version", `{"version":2,"files":{"/a":{"size":1}}}`, 0},
+		{"sans fichiers", `{"version":3}`, 0},
+		{"version courante", `{"version":3,"files":{"/a":{"size":1},"/b":{"size":2}}}`, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "absent.json")
+			if tt.content != "" {
+				path = filepath.Join(dir, "m.json")
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m := LoadManifest(path)
+			if m.Version != manifestVersion || m.Files == nil || len(m.Files) != tt.files {
+				t.Errorf("LoadManifest = version %d, %d fichiers ; veut %d, %d", m.Version, len(m.Files), manifestVersion, tt.files)
+			}
+		})
+	}
+}
+
+func TestManifestSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	path := filepath.Join(dir, ManifestName)
+	m := LoadManifest(path)
+	m.Files["/a.txt"] = ManifestEntry{Size: 3, SHA256: "abc", Info: FileInfo{Path: "/a.txt", Words: 2}}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o644 {
+		t.Errorf("droits %v, veut 0644", st.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("fichiers temporaires restants : %v", entries)
+	}
+	got := LoadManifest(path)
+	if e := got.Files["/a.txt"]; e.Size != 3 || e.SHA256 != "abc" || e.Info.Words != 2 {
+		t.Errorf("entrée relue %+v", e)
+	}
+}
+
+func TestManifestLookup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	write := func(content string, mtime time.Time) os.FileInfo {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		content string
+		mtime   time.Time
+		key     string
+		ok      bool
+	}{
+		{"inchangé", "un deux", t0, path, true},
+		// date seule changée : le contenu est comparé (SHA-256)
+		{"date seule", "un deux", t1, path, true},
+		{"contenu de même taille", "un dix!", t1, path, false},
+		{"taille", "un deux trois", t0, path, false},
+		{"clé inconnue", "un deux", t0, path + ".autre", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := write("un deux", t0)
+			sum, err := hashFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := LoadManifest("")
+			m.Files[path] = ManifestEntry{Size: st.Size(), ModTime: st.ModTime(), SHA256: sum,
+				Info: FileInfo{Path: "ancien", Size: st.Size(), ModTime: st.ModTime(), Words: 2}}
+
+			st = write(tt.content, tt.mtime)
+			e, ok := m.lookup(tt.key, path, st)
+			if ok != tt.ok {
+				t.Fatalf("lookup = %v, veut %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			// la date reprise est celle du fichier, pour la prochaine comparaison
+			if !e.ModTime.Equal(tt.mtime) || !e.Info.ModTime.Equal(tt.mtime) || e.Info.Path != path || e.Info.Words != 2 {
+				t.Errorf("entrée %+v", e)
+			}
+		})
+	}
+}
+
+func TestProcessBatchManifest(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	out := filepath.Join(dir, "out")
+	makeTree(t, data, map[string]string{"a.txt": "un deux", "b.txt": "trois", "c.txt": "quatre"}, nil)
+	opt := BatchOptions{
+		Report:   filepath.Join(out, "report.txt"),
+		Index:    filepath.Join(out, "index.txt"),
+		Merged:   filepath.Join(out, "merged.txt"),
+		Manifest: filepath.Join(out, ManifestName),
+		Root:     data,
+	}
+	// une entrée d'un autre lot, à conserver
+	other := filepath.Join(dir, "autre", "x.txt")
+	m := LoadManifest(opt.Manifest)
+	m.Files[other] = ManifestEntry{Size: 1}
+	if err := m.Save(opt.Manifest); err != nil {
+		t.Fatal(err)
+	}
+	run := func(opt BatchOptions) BatchResult {
+		t.Helper()
+		files, err := ListTxt(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := ProcessBatch(files, opt)
+		if err != nil || res.Err() != nil {
+			t.Fatal(err, res.Err())
+		}
+		return res
+	}
+	a := filepath.Join(data, "a.txt")
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		change  func()
+		full    bool
+		reused  int
+		removed int
+		words   int
+	}{
+		{"premier lot", nil, false, 0, 0, 4},
+		{"inchangé", nil, false, 3, 0, 4},
+		{"date seule", func() { os.Chtimes(a, later, later) }, false, 3, 0, 4},
+		{"même taille", func() {
+			os.WriteFile(a, []byte("un, deu"), 0o644)
+			os.Chtimes(a, later.Add(time.Minute), later.Add(time.Minute))
+		}, false, 2, 0, 4},
+		{"supprimé", func() { os.Remove(filepath.Join(data, "c.txt")) }, false, 2, 1, 3},
+		{"complet", nil, true, 0, 0, 3},
+	}
+	for _, tt := range tests {
+		if tt.change != nil {
+			tt.change()
+		}
+		o := opt
+		o.Full = tt.full
+		res := run(o)
+		words := 0
+		for _, f := range res.Files {
+			words += f.Words
+		}
+		if res.Reused != tt.reused || res.Removed != tt.removed || words != tt.words {
+			t.Errorf("%s : %d repris, %d retirés, %d mots ; veut %d, %d, %d",
+				tt.name, res.Reused, res.Removed, words, tt.reused, tt.removed, tt.words)
+		}
+		if _, ok := LoadManifest(opt.Manifest).Files[other]; !ok {
+			t.Errorf("%s : entrée hors du lot perdue", tt.name)
+		}
+	}
+}
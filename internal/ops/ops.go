@@ -2,55 +2,52 @@ package ops
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
-	"unicode"
+	"time"
+
+	"fileops/internal/textstat"
 )
 
-func ReadLines(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// FileInfo regroupe les statistiques d'un fichier texte.
+type FileInfo struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Lines   int       `json:"lines"`
+	Words   int       `json:"words"`
+	AvgLen  float64   `json:"avg_len"`
 
-	var lines []string
-	sc := bufio.NewScanner(file)
-	for sc.Scan() {
-		lines = append(lines, sc.Text())
-	}
-	return lines, sc.Err()
+	Vocab *textstat.Vocab `json:"vocab,omitempty"`
 }
 
-func PrintFileInfo(path string, lines []string) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
-	}
-
-	words, avgLen := statsWords(lines)
-
-	fmt.Printf("\n— Infos sur %s —\n", path)
-	fmt.Printf("Taille : %d o\n", info.Size())
-	fmt.Printf("Créé : %s\n", info.ModTime().Format("2006-01-02 15:04:05"))
-	fmt.Printf("Nb lignes : %d\n", len(lines))
-	fmt.Printf("Nb mots : %d (longueur moyenne %.1f)\n\n", words, avgLen)
-	return nil
+// WriteText affiche fi au format texte du menu.
+func (fi FileInfo) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "\n— Infos sur %s —\n", fi.Path)
+	fmt.Fprintf(w, "Taille : %d o\n", fi.Size)
+	fmt.Fprintf(w, "Créé : %s\n", fi.ModTime.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Nb lignes : %d\n", fi.Lines)
+	fmt.Fprintf(w, "Nb mots : %d (longueur moyenne %.1f)\n\n", fi.Words, fi.AvgLen)
 }
 
-func FilterLines(lines []string, kw, out string, keep bool) error {
-	var filtered []string
-	for _, l := range lines {
-		has := strings.Contains(strings.ToLower(l), strings.ToLower(kw))
-		if (keep && has) || (!keep && !has) {
-			filtered = append(filtered, l)
-		}
+// FilterLines écrit dans out les lignes de path retenues par f (keep) ou
+// rejetées (!keep), en une passe à mémoire bornée (voir Analyze).
+func FilterLines(path string, f *Filter, out string, keep bool) error {
+	opt := AnalyzeOptions{Filter: f}
+	if keep {
+		opt.Filtered = out
+	} else {
+		opt.Unfiltered = out
 	}
-	return WriteLines(filtered, out)
+	_, err := Analyze(path, opt)
+	return err
 }
 
 func WriteLines(lines []string, out string) error {
@@ -72,82 +69,315 @@ func WriteLines(lines []string, out string) error {
 	return w.Flush()
 }
 
-func ListTxt(dir string) ([]string, error) {
-	var files []string
-	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !d.IsDir() && strings.HasSuffix(d.Name(), ".txt") {
-			files = append(files, p)
-		}
+// BatchOptions désigne les fichiers produits par ProcessBatch.
+type BatchOptions struct {
+	Report, Index, Merged string
+	Vocab                 string // tableaux de fréquences ("" = aucun)
+	VocabTopN             int
+	Workers               int    // analyses simultanées (défaut DefaultWorkers)
+	Manifest              string // résultats par fichier réutilisés d'un lot à l'autre ("" = aucun)
+	Full                  bool   // ignore le manifeste et réanalyse tout
+	// Root : répertoire du lot ; les entrées du manifeste hors de Root
+	// sont conservées telles quelles ("" = tout le manifeste est au lot)
+	Root string
+}
+
+// DefaultWorkers est la taille par défaut du pool de ProcessBatch.
+const DefaultWorkers = 4
+
+// FileError est l'échec de l'analyse d'un fichier du lot.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string { return e.Path + " : " + e.Err.Error() }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// BatchResult regroupe les fichiers analysés et ceux en échec, triés par
+// chemin. Reused compte les fichiers repris du manifeste sans analyse,
+// Removed ceux du manifeste qui ne font plus partie du lot.
+type BatchResult struct {
+	Files           []FileInfo
+	Failed          []*FileError
+	Reused, Removed int
+}
+
+// Err renvoie nil si tous les fichiers ont été analysés.
+func (r BatchResult) Err() error {
+	if len(r.Failed) == 0 {
 		return nil
-	})
-	return files, err
+	}
+	return fmt.Errorf("%d fichier(s) sur %d en échec", len(r.Failed), len(r.Files)+len(r.Failed))
 }
 
-func ProcessBatch(files []string, report, index, merged string) error {
+// ProcessBatch analyse files avec Workers analyses simultanées au plus,
+// puis écrit report, index et merged (et vocab) dans l'ordre des chemins,
+// quel que soit l'ordre de fin des analyses. Un fichier illisible n'arrête
+// pas le lot : il figure dans Failed et dans la section d'erreurs du
+// rapport. L'erreur renvoyée ne concerne que l'écriture des résultats.
+//
+// Avec Manifest, seuls les fichiers nouveaux ou modifiés sont analysés ;
+// les autres sont repris du manifeste. Ses entrées sous Root sont ensuite
+// remplacées par les fichiers du lot, les autres sont gardées. Vocab fait
+// tout relire : le vocabulaire du lot cumule les comptes complets de
+// chaque fichier.
+func ProcessBatch(files []string, opt BatchOptions) (BatchResult, error) {
+	files = slices.Clone(files)
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	topN := 0
+	if opt.Vocab != "" {
+		topN = max(opt.VocabTopN, 1)
+	}
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	prev := &Manifest{Files: map[string]ManifestEntry{}}
+	if opt.Manifest != "" {
+		prev = LoadManifest(opt.Manifest)
+	}
+	// le vocabulaire du lot demande les comptes complets de chaque
+	// fichier : tous sont relus, le manifeste est seulement mis à jour
+	cache := prev
+	if opt.Full || topN > 0 {
+		cache = &Manifest{Files: map[string]ManifestEntry{}}
+	}
 	var (
-		wg          sync.WaitGroup
-		mu          sync.Mutex
-		indexLines  []string
-		reportLines []string
-		mergedLines []string
+		mu  sync.Mutex
+		all *textstat.Counter
 	)
+	if topN > 0 {
+		all = textstat.NewCounter()
+	}
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = manifestKey(f)
+	}
+	root := ""
+	if opt.Root != "" {
+		root = manifestKey(opt.Root)
+	}
 
-	for _, f := range files {
+	entries := make([]ManifestEntry, len(files))
+	reused := make([]bool, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(files)); w++ {
 		wg.Add(1)
-		go func(file string) {
+		go func() {
 			defer wg.Done()
-
-			info, err := os.Stat(file)
-			if err != nil {
-				return
+			for i := range jobs {
+				var c *textstat.Counter
+				if all != nil {
+					c = textstat.NewCounter()
+				}
+				entries[i], reused[i], errs[i] = batchEntry(files[i], keys[i], cache, opt.Manifest != "", c, topN)
+				if c != nil && errs[i] == nil {
+					mu.Lock()
+					all.Merge(c)
+					mu.Unlock()
+				}
 			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-			lines, err := ReadLines(file)
-			if err != nil {
-				return
-			}
-			words, avg := statsWords(lines)
+	res := BatchResult{Files: []FileInfo{}}
+	next := &Manifest{Version: manifestVersion, Files: map[string]ManifestEntry{}}
+	inBatch := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		inBatch[k] = true
+	}
+	for k, e := range prev.Files {
+		switch {
+		case !underRoot(k, root):
+			next.Files[k] = e
+		case !inBatch[k]:
+			res.Removed++
+		}
+	}
+	for i, f := range files {
+		if errs[i] != nil {
+			res.Failed = append(res.Failed, &FileError{Path: f, Err: errs[i]})
+			continue
+		}
+		e := entries[i]
+		res.Files = append(res.Files, e.Info)
+		e.Info.Vocab = nil
+		next.Files[keys[i]] = e
+		if reused[i] {
+			res.Reused++
+		}
+	}
+	indexLines := make([]string, len(res.Files))
+	reportLines := make([]string, len(res.Files))
+	for i, r := range res.Files {
+		indexLines[i] = r.IndexLine()
+		reportLines[i] = r.ReportLine()
+	}
+	if len(res.Failed) > 0 {
+		if len(reportLines) > 0 {
+			reportLines = append(reportLines, "")
+		}
+		reportLines = append(reportLines, fmt.Sprintf("--- Erreurs (%d) ---", len(res.Failed)))
+		for _, f := range res.Failed {
+			reportLines = append(reportLines, f.Error())
+		}
+	}
+
+	if err := WriteLines(indexLines, opt.Index); err != nil {
+		return res, err
+	}
+	if err := WriteLines(reportLines, opt.Report); err != nil {
+		return res, err
+	}
+	if opt.Vocab != "" {
+		if err := writeBatchVocab(opt.Vocab, all.Vocab(topN), res.Files); err != nil {
+			return res, err
+		}
+	}
+	if err := mergeFiles(res.Files, opt.Merged); err != nil {
+		return res, err
+	}
+	if opt.Manifest != "" {
+		return res, next.Save(opt.Manifest)
+	}
+	return res, nil
+}
 
-			mu.Lock()
-			indexLines = append(indexLines,
-				fmt.Sprintf("%s | %d o | %s",
-					file, info.Size(),
-					info.ModTime().Format("2006-01-02 15:04:05")))
-			reportLines = append(reportLines,
-				fmt.Sprintf("%s → %d lignes, %d mots (moy. %.1f)",
-					filepath.Base(file), len(lines), words, avg))
-			mergedLines = append(mergedLines, lines...)
-			mu.Unlock()
-		}(f)
+// batchEntry reprend path de cache s'il n'a pas changé, sinon l'analyse.
+// L'empreinte, calculée dans la même lecture que les statistiques, n'est
+// utile que si le manifeste est utilisé (hash) ; c reçoit les mots du
+// fichier (nil = pas de vocabulaire).
+func batchEntry(path, key string, cache *Manifest, hash bool, c *textstat.Counter, topN int) (ManifestEntry, bool, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	if e, ok := cache.lookup(key, path, st); ok {
+		return e, true, nil
 	}
+	opt := AnalyzeOptions{VocabTopN: topN, Counter: c}
+	if hash {
+		opt.Hash = sha256.New()
+	}
+	info, err := Analyze(path, opt)
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	e := ManifestEntry{Size: info.Size, ModTime: info.ModTime, Info: info}
+	if hash {
+		e.SHA256 = hex.EncodeToString(opt.Hash.Sum(nil))
+	}
+	return e, false, nil
+}
 
-	wg.Wait()
+// manifestKey renvoie le chemin absolu de path (path s'il est introuvable).
+func manifestKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// underRoot indique si le chemin absolu key est dans root ("" = partout).
+func underRoot(key, root string) bool {
+	return root == "" || key == root || strings.HasPrefix(key, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
 
-	if err := WriteLines(indexLines, index); err != nil {
+// writeBatchVocab écrit le vocabulaire du lot puis celui de chaque fichier.
+func writeBatchVocab(path string, all textstat.Vocab, results []FileInfo) error {
+	var outs outputs
+	defer outs.close()
+	w, err := outs.open(path)
+	if err != nil {
 		return err
 	}
-	if err := WriteLines(reportLines, report); err != nil {
+	all.WriteText(w, fmt.Sprintf("ensemble du lot (%d fichiers)", len(results)))
+	for _, r := range results {
+		if r.Vocab != nil {
+			r.Vocab.WriteText(w, r.Path)
+		}
+	}
+	return outs.close()
+}
+
+// WriteVocab écrit le vocabulaire de fi dans path.
+func WriteVocab(path string, fi FileInfo) error {
+	if fi.Vocab == nil {
+		return nil
+	}
+	var outs outputs
+	defer outs.close()
+	w, err := outs.open(path)
+	if err != nil {
 		return err
 	}
-	return WriteLines(mergedLines, merged)
+	fi.Vocab.WriteText(w, fi.Path)
+	return outs.close()
 }
 
-func statsWords(lines []string) (int, float64) {
-	var count, sum int
-	for _, l := range lines {
-		for _, tok := range strings.Fields(l) {
-			if unicode.IsDigit(rune(tok[0])) {
-				continue
-			}
-			count++
-			sum += len(tok)
+// mergeFiles concatène les fichiers analysés dans out, fichier par fichier,
+// sans les charger en mémoire.
+func mergeFiles(results []FileInfo, out string) error {
+	var outs outputs
+	defer outs.close()
+	w, err := outs.open(out)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := appendFile(w, r.Path); err != nil {
+			return err
 		}
 	}
-	if count == 0 {
-		return 0, 0
+	return outs.close()
+}
+
+// appendFile recopie path dans w en terminant par un saut de ligne.
+func appendFile(w *bufio.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return err
 	}
-	return count, float64(sum) / float64(count)
+	if n == 0 {
+		return nil
+	}
+	if _, err := f.Seek(-1, io.SeekEnd); err != nil {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.Read(last); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		return w.WriteByte('\n')
+	}
+	return nil
+}
+
+// IndexLine formate fi pour index.txt.
+func (fi FileInfo) IndexLine() string {
+	return fmt.Sprintf("%s | %d o | %s",
+		fi.Path, fi.Size, fi.ModTime.Format("2006-01-02 15:04:05"))
+}
+
+// ReportLine formate fi pour report.txt.
+func (fi FileInfo) ReportLine() string {
+	return fmt.Sprintf("%s → %d lignes, %d mots (moy. %.1f)",
+		filepath.Base(fi.Path), fi.Lines, fi.Words, fi.AvgLen)
 }
@@ -0,0 +1,338 @@
+package ops
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Politiques des liens symboliques de Selection.
+const (
+	SymlinksIgnore = "ignore" // liens ignorés
+	SymlinksFiles  = "files"  // liens vers des fichiers suivis (défaut)
+	SymlinksAll    = "all"    // liens vers des répertoires parcourus aussi
+)
+
+// Selection choisit les fichiers d'un lot. Les motifs portent sur le
+// chemin relatif au répertoire de départ (séparateur /) : « *.log »
+// s'applique au nom à tout niveau, « logs/*.txt » au chemin complet, et
+// « ** » traverse les répertoires.
+type Selection struct {
+	Include []string // au moins un motif doit correspondre (vide = tout)
+	Exclude []string
+	Exts    []string // extensions retenues, « .txt » (vide = toutes)
+
+	MinSize, MaxSize int64     // octets (0 = pas de borne)
+	Since, Before    time.Time // date de modification (zéro = pas de borne)
+
+	MaxDepth   int    // 1 = fichiers du répertoire seulement (0 = illimité)
+	Symlinks   string // SymlinksIgnore, SymlinksFiles ou SymlinksAll
+	IgnoreFile string // nom du fichier d'exclusion ("" = aucun)
+}
+
+// String résume les filtres actifs (« tout » sans filtre).
+func (s Selection) String() string {
+	var f []string
+	if len(s.Exts) > 0 {
+		f = append(f, "ext "+strings.Join(s.Exts, ","))
+	}
+	if len(s.Include) > 0 {
+		f = append(f, "inclus "+strings.Join(s.Include, ","))
+	}
+	if len(s.Exclude) > 0 {
+		f = append(f, "exclus "+strings.Join(s.Exclude, ","))
+	}
+	if s.MinSize > 0 {
+		f = append(f, fmt.Sprintf(">= %d o", s.MinSize))
+	}
+	if s.MaxSize > 0 {
+		f = append(f, fmt.Sprintf("<= %d o", s.MaxSize))
+	}
+	if !s.Since.IsZero() {
+		f = append(f, "depuis "+s.Since.Format("2006-01-02 15:04"))
+	}
+	if !s.Before.IsZero() {
+		f = append(f, "avant "+s.Before.Format("2006-01-02 15:04"))
+	}
+	if s.MaxDepth > 0 {
+		f = append(f, fmt.Sprintf("profondeur %d", s.MaxDepth))
+	}
+	if len(f) == 0 {
+		return "tout"
+	}
+	return strings.Join(f, ", ")
+}
+
+// ListTxt renvoie les fichiers .txt de dir (et de ses sous-répertoires).
+func ListTxt(dir string) ([]string, error) {
+	return Selection{Exts: []string{".txt"}}.List(dir)
+}
+
+// List parcourt dir et renvoie les fichiers retenus, triés.
+func (s Selection) List(dir string) ([]string, error) {
+	switch s.Symlinks {
+	case "":
+		s.Symlinks = SymlinksFiles
+	case SymlinksIgnore, SymlinksFiles, SymlinksAll:
+	default:
+		return nil, fmt.Errorf("politique de liens inconnue %q (ignore, files, all)", s.Symlinks)
+	}
+	for _, p := range append(slices.Clone(s.Include), s.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(p, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("motif invalide %q", p)
+		}
+	}
+	w := walker{sel: s, visited: map[string]bool{}}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		w.visited[real] = true
+	}
+	if err := w.walk(dir, "", 1); err != nil {
+		return nil, err
+	}
+	slices.Sort(w.files)
+	return w.files, nil
+}
+
+type walker struct {
+	sel     Selection
+	rules   []ignoreRule
+	visited map[string]bool // répertoires réels déjà parcourus (boucles)
+	files   []string
+}
+
+// walk parcourt dir, de chemin relatif rel et de profondeur depth.
+func (w *walker) walk(dir, rel string, depth int) error {
+	if w.sel.IgnoreFile != "" {
+		rules, err := readIgnore(filepath.Join(dir, w.sel.IgnoreFile), rel)
+		if err != nil {
+			return err
+		}
+		w.rules = append(w.rules, rules...)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		r := path.Join(rel, e.Name())
+		isDir := e.IsDir()
+
+		if e.Type()&fs.ModeSymlink != 0 {
+			if w.sel.Symlinks == SymlinksIgnore {
+				continue
+			}
+			st, err := os.Stat(p)
+			if err != nil {
+				// lien cassé : signalé comme fichier, l'analyse échouera
+				if w.keepFile(r, nil) {
+					w.files = append(w.files, p)
+				}
+				continue
+			}
+			isDir = st.IsDir()
+			if isDir && w.sel.Symlinks != SymlinksAll {
+				continue
+			}
+		}
+
+		if isDir {
+			if w.ignored(r, true) || (w.sel.MaxDepth > 0 && depth >= w.sel.MaxDepth) {
+				continue
+			}
+			real, err := filepath.EvalSymlinks(p)
+			if err != nil || w.visited[real] {
+				continue
+			}
+			w.visited[real] = true
+			if err := w.walk(p, r, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.Name() == w.sel.IgnoreFile || !w.keepName(r) {
+			continue
+		}
+		st, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if w.keepFile(r, st) {
+			w.files = append(w.files, p)
+		}
+	}
+	return nil
+}
+
+// keepName applique extensions, motifs et fichier d'exclusion.
+func (w *walker) keepName(rel string) bool {
+	s := w.sel
+	if len(s.Exts) > 0 && !slices.ContainsFunc(s.Exts, func(ext string) bool {
+		return strings.EqualFold(path.Ext(rel), ext)
+	}) {
+		return false
+	}
+	if len(s.Include) > 0 && !slices.ContainsFunc(s.Include, func(p string) bool { return matchGlob(p, rel) }) {
+		return false
+	}
+	if slices.ContainsFunc(s.Exclude, func(p string) bool { return matchGlob(p, rel) }) {
+		return false
+	}
+	return !w.ignored(rel, false)
+}
+
+// keepFile applique en plus taille et date (st nil : lien cassé, gardé
+// si son nom est retenu et qu'aucune borne ne s'applique).
+func (w *walker) keepFile(rel string, st fs.FileInfo) bool {
+	s := w.sel
+	if st == nil {
+		return s.MinSize == 0 && s.MaxSize == 0 && s.Since.IsZero() && s.Before.IsZero() && w.keepName(rel)
+	}
+	if s.MinSize > 0 && st.Size() < s.MinSize || s.MaxSize > 0 && st.Size() > s.MaxSize {
+		return false
+	}
+	if !s.Since.IsZero() && st.ModTime().Before(s.Since) || !s.Before.IsZero() && !st.ModTime().Before(s.Before) {
+		return false
+	}
+	return true
+}
+
+// matchGlob compare rel à un motif : sans « / », au nom seul, à tout
+// niveau ; avec « / » (même seulement en tête, « /a.txt »), au chemin
+// depuis la racine. « ** » correspond à zéro, un ou plusieurs répertoires.
+func matchGlob(pattern, rel string) bool {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !anchored && !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchParts(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchParts(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchParts(pat[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ignoreRule est une ligne d'un fichier d'exclusion, à la manière de
+// .gitignore.
+type ignoreRule struct {
+	base    string // répertoire du fichier, relatif à la racine
+	pattern string
+	negate  bool // « !motif » : réinclut
+	dirOnly bool // « motif/ » : répertoires seulement
+}
+
+// readIgnore lit un fichier d'exclusion (absent = aucune règle).
+func readIgnore(file, base string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		// « /motif » et « a/b » sont ancrés au répertoire du fichier
+		if strings.Contains(line, "/") && !strings.HasPrefix(line, "**/") {
+			line = "/" + strings.TrimPrefix(line, "/")
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", file, err)
+	}
+	return rules, nil
+}
+
+// ignored applique les règles dans l'ordre : la dernière qui correspond
+// l'emporte.
+func (w *walker) ignored(rel string, isDir bool) bool {
+	ign := false
+	for _, r := range w.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, r.base+"/")
+		}
+		if matchGlob(r.pattern, sub) {
+			ign = !r.negate
+		}
+	}
+	return ign
+}
+
+// ParseSize lit une taille : 512, 10K, 1.5M, 2G (puissances de 1024).
+func ParseSize(s string) (int64, error) {
+	m := sizeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("taille invalide %q (ex. 512, 10K, 2M)", s)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	mult := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}[strings.ToUpper(m[2])]
+	return int64(n * mult), nil
+}
+
+var sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kKmMgGtT]?)[oOB]?$`)
+
+// ParseTime lit une date (2006-01-02, 2006-01-02T15:04 ou RFC 3339) ou
+// une durée avant now (« 72h » = il y a trois jours).
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date invalide %q (ex. 2024-05-01 ou 72h)", s)
+}
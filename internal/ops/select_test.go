@@ -0,0 +1,202 @@
+package ops
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.log", "a.log", true},
+		{"*.log", "x/y/a.log", true},
+		{"*.log", "a.txt", false},
+		// « / » en tête : ancré à la racine, même sans autre « / »
+		{"/a.txt", "a.txt", true},
+		{"/a.txt", "sub/a.txt", false},
+		{"/*.txt", "sub/a.txt", false},
+		{"logs/*.txt", "logs/a.txt", true},
+		{"logs/*.txt", "x/logs/a.txt", false},
+		{"logs/*.txt", "logs/x/a.txt", false},
+		{"/logs/*.txt", "logs/a.txt", true},
+		{"**/a.txt", "a.txt", true},
+		{"**/a.txt", "x/y/a.txt", true},
+		{"logs/**/*.txt", "logs/a.txt", true},
+		{"logs/**/*.txt", "logs/x/y/a.txt", true},
+		{"logs/**", "logs/x/a.txt", true},
+		{"logs/**", "a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, veut %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// makeTree crée les fichiers (chemin → contenu) et les liens (chemin →
+// cible) sous root.
+func makeTree(t *testing.T, root string, files, links map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("liens symboliques indisponibles : %v", err)
+		}
+	}
+}
+
+// relList liste les fichiers retenus, relatifs à root.
+func relList(t *testing.T, s Selection, root string) []string {
+	t.Helper()
+	files, err := s.List(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rel []string
+	for _, f := range files {
+		r, err := filepath.Rel(root, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestSelectionList(t *testing.T) {
+	root := t.TempDir()
+	ext := t.TempDir()
+	makeTree(t, ext, map[string]string{"f.txt": "x"}, nil)
+	makeTree(t, root, map[string]string{
+		"a.txt":          "a",
+		"b.log":          "b",
+		"big.txt":        strings.Repeat("x", 2048),
+		"keep.log":       "k",
+		"build/out.txt":  "o",
+		"sub/a.txt":      "a",
+		"sub/c.log":      "c",
+		"sub/deep/e.txt": "e",
+	}, map[string]string{
+		"link.txt":   filepath.Join(root, "sub", "a.txt"),
+		"broken.txt": filepath.Join(root, "absent"),
+		"ext":        ext,
+		"sub/loop":   root,
+	})
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "b.log"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	all := []string{"a.txt", "b.log", "big.txt", "broken.txt", "build/out.txt", "keep.log",
+		"link.txt", "sub/a.txt", "sub/c.log", "sub/deep/e.txt"}
+	tests := []struct {
+		name string
+		sel  Selection
+		want []string
+	}{
+		{"tout", Selection{}, all},
+		{"extension", Selection{Exts: []string{".TXT"}},
+			[]string{"a.txt", "big.txt", "broken.txt", "build/out.txt", "link.txt", "sub/a.txt", "sub/deep/e.txt"}},
+		{"nom à tout niveau", Selection{Include: []string{"*.log"}}, []string{"b.log", "keep.log", "sub/c.log"}},
+		{"ancré", Selection{Include: []string{"/a.txt"}}, []string{"a.txt"}},
+		{"chemin", Selection{Include: []string{"sub/*.txt"}}, []string{"sub/a.txt"}},
+		{"double étoile", Selection{Include: []string{"sub/**"}}, []string{"sub/a.txt", "sub/c.log", "sub/deep/e.txt"}},
+		{"exclusion", Selection{Include: []string{"*.log", "*.txt"}, Exclude: []string{"b*", "/sub/**"}},
+			[]string{"a.txt", "build/out.txt", "keep.log", "link.txt"}},
+		{"profondeur", Selection{MaxDepth: 1},
+			[]string{"a.txt", "b.log", "big.txt", "broken.txt", "keep.log", "link.txt"}},
+		{"profondeur 2", Selection{MaxDepth: 2, Include: []string{"*.txt"}},
+			[]string{"a.txt", "big.txt", "broken.txt", "build/out.txt", "link.txt", "sub/a.txt"}},
+		// bornes : le lien cassé est écarté
+		{"taille", Selection{MinSize: 1024}, []string{"big.txt"}},
+		{"taille max", Selection{MaxSize: 1, Include: []string{"/*"}},
+			[]string{"a.txt", "b.log", "keep.log", "link.txt"}},
+		{"date", Selection{Before: time.Now().Add(-24 * time.Hour)}, []string{"b.log"}},
+		{"liens ignorés", Selection{Symlinks: SymlinksIgnore},
+			[]string{"a.txt", "b.log", "big.txt", "build/out.txt", "keep.log", "sub/a.txt", "sub/c.log", "sub/deep/e.txt"}},
+		// sub/loop ramène à la racine déjà parcourue
+		{"liens suivis", Selection{Symlinks: SymlinksAll}, append(slices.Clone(all), "ext/f.txt")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := relList(t, tt.sel, root)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("List = %q\nveut %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSelectionIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, map[string]string{
+		".fileopsignore": "# journaux\n*.log\n!keep.log\nbuild/\n/a.txt\n",
+		"a.txt":          "a",
+		"b.log":          "b",
+		"keep.log":       "k",
+		"build/out.txt":  "o",
+		"sub/a.txt":      "a",
+		"sub/build":      "fichier, pas répertoire",
+		"sub/c.log":      "c",
+		"sub/keep.log":   "k",
+		// règles du sous-répertoire, après celles du parent
+		"sub/.fileopsignore": "!c.log\nd/\n/x/y.txt\n",
+		"sub/d/z.txt":        "z",
+		"sub/x/y.txt":        "y",
+		"sub/w/x/y.txt":      "y",
+	}, nil)
+
+	tests := []struct {
+		name string
+		sel  Selection
+		want []string
+	}{
+		{"règles", Selection{IgnoreFile: ".fileopsignore"},
+			[]string{"keep.log", "sub/a.txt", "sub/build", "sub/c.log", "sub/keep.log", "sub/w/x/y.txt"}},
+		{"sans fichier d'exclusion", Selection{},
+			[]string{".fileopsignore", "a.txt", "b.log", "build/out.txt", "keep.log", "sub/.fileopsignore",
+				"sub/a.txt", "sub/build", "sub/c.log", "sub/d/z.txt", "sub/keep.log", "sub/w/x/y.txt", "sub/x/y.txt"}},
+		{"nom absent", Selection{IgnoreFile: ".absent"}, []string{".fileopsignore", "a.txt", "b.log",
+			"build/out.txt", "keep.log", "sub/.fileopsignore", "sub/a.txt", "sub/build", "sub/c.log",
+			"sub/d/z.txt", "sub/keep.log", "sub/w/x/y.txt", "sub/x/y.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relList(t, tt.sel, root); !slices.Equal(got, tt.want) {
+				t.Errorf("List = %q\nveut %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectionErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, s := range []Selection{
+		{Symlinks: "parfois"},
+		{Include: []string{"[a-"}},
+		{Exclude: []string{"x/[/y"}},
+	} {
+		if _, err := s.List(dir); err == nil {
+			t.Errorf("List(%+v) : pas d'erreur", s)
+		}
+	}
+	if _, err := (Selection{}).List(filepath.Join(dir, "absent")); err == nil {
+		t.Error("pas d'erreur pour un répertoire absent")
+	}
+}
@@ -0,0 +1,240 @@
+package ops
+
+import (
+	"bufio"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fileops/internal/textstat"
+)
+
+// MaxLineSize borne la taille d'une ligne lue par Analyze.
+const MaxLineSize = 16 << 20
+
+// AnalyzeOptions décrit les sorties calculées pendant le parcours du fichier.
+// Un chemin vide désactive la sortie correspondante.
+type AnalyzeOptions struct {
+	Filter     *Filter // nil retient toutes les lignes
+	Filtered   string  // lignes retenues par Filter
+	Unfiltered string  // lignes rejetées par Filter
+	Head       int
+	HeadOut    string
+	Tail       int
+	TailOut    string
+
+	// VocabTopN > 0 remplit FileInfo.Vocab ; les comptes complets ne
+	// vivent que le temps de la lecture, sauf s'ils vont dans Counter
+	VocabTopN int
+	Counter   *textstat.Counter // reçoit les mots du fichier (nil = aucun)
+
+	// Hash reçoit le contenu lu, dans la même passe (nil = aucun)
+	Hash hash.Hash
+}
+
+// Analyze lit path en une seule passe, à mémoire bornée : statistiques,
+// sorties filtrées et head écrites au fil de l'eau, tail gardé dans un
+// tampon circulaire de opt.Tail lignes.
+func Analyze(path string, opt AnalyzeOptions) (FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	var outs outputs
+	defer outs.close()
+	filtered, err := outs.open(opt.Filtered)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	unfiltered, err := outs.open(opt.Unfiltered)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	head, err := outs.open(opt.HeadOut)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	var (
+		wc    wordCounter
+		vc    *textstat.Counter
+		lines int
+		tail  = newRing(opt.Tail)
+	)
+	switch {
+	case opt.Counter != nil:
+		vc = opt.Counter
+	case opt.VocabTopN > 0:
+		vc = textstat.NewCounter()
+	}
+	var r io.Reader = f
+	if opt.Hash != nil {
+		r = io.TeeReader(f, opt.Hash)
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	for sc.Scan() {
+		l := sc.Text()
+		lines++
+		wc.add(l)
+		if vc != nil {
+			vc.AddLine(l)
+		}
+
+		if filtered != nil || unfiltered != nil {
+			w := unfiltered
+			if opt.Filter.Match(l) {
+				w = filtered
+			}
+			if err := writeLine(w, l); err != nil {
+				return FileInfo{}, err
+			}
+		}
+		if lines <= opt.Head {
+			if err := writeLine(head, l); err != nil {
+				return FileInfo{}, err
+			}
+		}
+		tail.push(l)
+	}
+	if err := sc.Err(); err != nil {
+		return FileInfo{}, err
+	}
+
+	if opt.TailOut != "" {
+		if err := WriteLines(tail.lines(), opt.TailOut); err != nil {
+			return FileInfo{}, err
+		}
+	}
+	if err := outs.close(); err != nil {
+		return FileInfo{}, err
+	}
+
+	words, avg := wc.result()
+	fi := FileInfo{
+		Path:    path,
+		Size:    st.Size(),
+		ModTime: st.ModTime(),
+		Lines:   lines,
+		Words:   words,
+		AvgLen:  avg,
+	}
+	if opt.VocabTopN > 0 {
+		v := vc.Vocab(opt.VocabTopN)
+		fi.Vocab = &v
+	}
+	return fi, nil
+}
+
+// wordCounter cumule le nombre de mots (hors nombres) et leur longueur,
+// sans mémoriser les mots.
+type wordCounter struct {
+	count, sum int
+}
+
+func (wc *wordCounter) add(line string) {
+	for _, tok := range strings.Fields(line) {
+		if r, _ := utf8.DecodeRuneInString(tok); unicode.IsDigit(r) {
+			continue
+		}
+		wc.count++
+		wc.sum += len(tok)
+	}
+}
+
+func (wc *wordCounter) result() (int, float64) {
+	if wc.count == 0 {
+		return 0, 0
+	}
+	return wc.count, float64(wc.sum) / float64(wc.count)
+}
+
+// ring garde les n dernières lignes vues.
+type ring struct {
+	buf  []string
+	next int
+	full bool
+}
+
+func newRing(n int) *ring {
+	return &ring{buf: make([]string, max(n, 0))}
+}
+
+func (r *ring) push(l string) {
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.next] = l
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// lines renvoie le contenu du tampon, de la plus ancienne à la plus récente.
+func (r *ring) lines() []string {
+	if !r.full {
+		return r.buf[:r.next]
+	}
+	return append(append([]string(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
+
+// outputs regroupe les fichiers de sortie ouverts par Analyze.
+type outputs struct {
+	files   []*os.File
+	writers []*bufio.Writer
+}
+
+// open crée path (nil si path est vide).
+func (o *outputs) open(path string) (*bufio.Writer, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	o.files = append(o.files, f)
+	o.writers = append(o.writers, w)
+	return w, nil
+}
+
+// close vide et ferme toutes les sorties ; les appels suivants sont sans effet.
+func (o *outputs) close() error {
+	var first error
+	for i, f := range o.files {
+		if err := o.writers[i].Flush(); err != nil && first == nil {
+			first = err
+		}
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	o.files, o.writers = nil, nil
+	return first
+}
+
+func writeLine(w *bufio.Writer, l string) error {
+	if w == nil {
+		return nil
+	}
+	if _, err := w.WriteString(l); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
@@ -0,0 +1,20 @@
+package proc
+
+import (
+	"errors"
+	"syscall"
+)
+
+// killLinux envoie SIGTERM (ou SIGKILL si force) sans passer par kill(1).
+func killLinux(pid int, force bool) error {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// isGone indique que le processus visé n'existe plus.
+func isGone(err error) bool {
+	return errors.Is(err, syscall.ESRCH)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package proc
+
+import "errors"
+
+func killLinux(pid int, force bool) error {
+	return errors.New("OS non supporté")
+}
+
+// isGone : kill(1) et taskkill ne distinguent pas un processus disparu.
+func isGone(err error) bool {
+	return false
+}
@@ -2,17 +2,31 @@ package proc
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// Process décrit un processus. Les tailles mémoire sont en octets ;
+// UID vaut -1 quand l'OS n'en fournit pas (Windows).
 type Process struct {
-	PID  int
-	Name string
+	PID       int           `json:"pid"`
+	PPID      int           `json:"ppid"`
+	Name      string        `json:"name"`
+	UID       int           `json:"uid"`
+	User      string        `json:"user"`
+	State     string        `json:"state"`
+	RSS       uint64        `json:"rss"`
+	VSZ       uint64        `json:"vsz"`
+	CPUTime   time.Duration `json:"cpu_time"`
+	StartTime time.Time     `json:"start_time"`
+	Threads   int           `json:"threads"`
+	Args      []string      `json:"args"`
 }
 
 // LISTE DES PROCESSUS
@@ -23,52 +37,198 @@ func List() ([]Process, error) {
 		return listWindows()
 	case "darwin":
 		return listMac()
+	case "linux":
+		return ListProcFS(procRoot)
 	default:
 		return nil, errors.New("OS non supporté pour ProcessOps")
 	}
 }
 
+// winProcess est une ligne de Win32_Process convertie par PowerShell.
+type winProcess struct {
+	ProcessId       int
+	ParentProcessId int
+	CommandLine     string
+	ThreadCount     int
+	VirtualSize     uint64
+	KernelModeTime  uint64
+	UserModeTime    uint64
+	Start           int64
+}
+
+const winQuery = `Get-CimInstance Win32_Process | Select-Object ProcessId,ParentProcessId,CommandLine,ThreadCount,VirtualSize,KernelModeTime,UserModeTime,@{n='Start';e={if ($_.CreationDate) {([DateTimeOffset]$_.CreationDate).ToUnixTimeMilliseconds()} else {0}}} | ConvertTo-Json -Compress`
+
+// listWindows combine tasklist /V (nom, utilisateur, état, mémoire)
+// et Win32_Process (parent, threads, temps CPU, démarrage, ligne de commande).
 func listWindows() ([]Process, error) {
-	out, err := exec.Command("tasklist", "/FO", "CSV").Output()
+	out, err := exec.Command("tasklist", "/V", "/FO", "CSV").Output()
 	if err != nil {
 		return nil, err
 	}
 	lines := bytes.Split(out, []byte{'\n'})
 	var procs []Process
 	for _, raw := range lines[1:] {
-		fields := parseCSV(raw)
-		if len(fields) < 2 {
+		fields := parseCSV(bytes.TrimSpace(raw))
+		if len(fields) < 8 {
 			continue
 		}
 		pid, _ := strconv.Atoi(fields[1])
-		procs = append(procs, Process{PID: pid, Name: strings.Trim(fields[0], "\"")})
+		procs = append(procs, Process{
+			PID:     pid,
+			Name:    fields[0],
+			UID:     -1,
+			State:   fields[5],
+			RSS:     parseWinMem(fields[4]),
+			User:    fields[6],
+			CPUTime: parseCPUTime(fields[7]),
+		})
+	}
+
+	// informations complémentaires : facultatives si PowerShell échoue
+	out, err = exec.Command("powershell", "-NoProfile", "-Command", winQuery).Output()
+	if err != nil {
+		return procs, nil
+	}
+	var extra []winProcess
+	if err := json.Unmarshal(out, &extra); err != nil {
+		return procs, nil
+	}
+	byPID := make(map[int]winProcess, len(extra))
+	for _, w := range extra {
+		byPID[w.ProcessId] = w
+	}
+	for i := range procs {
+		w, ok := byPID[procs[i].PID]
+		if !ok {
+			continue
+		}
+		procs[i].PPID = w.ParentProcessId
+		procs[i].Threads = w.ThreadCount
+		procs[i].VSZ = w.VirtualSize
+		// temps exprimés en unités de 100 ns
+		procs[i].CPUTime = time.Duration(w.KernelModeTime+w.UserModeTime) * 100
+		if w.Start > 0 {
+			procs[i].StartTime = time.UnixMilli(w.Start)
+		}
+		procs[i].Args = splitCommandLine(w.CommandLine)
 	}
 	return procs, nil
 }
 
+// listMac utilise ps ; macOS n'expose pas le nombre de threads par
+// processus dans ps, Threads reste donc à 0.
 func listMac() ([]Process, error) {
-	out, err := exec.Command("ps", "-Ao", "pid,comm").Output()
+	out, err := exec.Command("ps", "-Ao",
+		"pid=,ppid=,uid=,user=,state=,rss=,vsz=,time=,lstart=,comm=").Output()
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(string(out), "\n")
 	var procs []Process
-	for _, l := range lines[1:] {
-		l = strings.TrimSpace(l)
-		if l == "" {
+	for _, l := range strings.Split(string(out), "\n") {
+		parts := strings.Fields(l)
+		// 8 champs simples + lstart (5 mots) + comm
+		if len(parts) < 14 {
 			continue
 		}
+		pid, _ := strconv.Atoi(parts[0])
+		ppid, _ := strconv.Atoi(parts[1])
+		uid, _ := strconv.Atoi(parts[2])
+		rss, _ := strconv.ParseUint(parts[5], 10, 64)
+		vsz, _ := strconv.ParseUint(parts[6], 10, 64)
+		start, _ := time.ParseInLocation("Mon Jan 2 15:04:05 2006",
+			strings.Join(parts[8:13], " "), time.Local)
+		procs = append(procs, Process{
+			PID:       pid,
+			PPID:      ppid,
+			Name:      strings.Join(parts[13:], " "),
+			UID:       uid,
+			User:      parts[3],
+			State:     parts[4],
+			RSS:       rss * 1024,
+			VSZ:       vsz * 1024,
+			CPUTime:   parseCPUTime(parts[7]),
+			StartTime: start,
+		})
+	}
+
+	// ps ne renvoie que la commande déjà jointe : découpage sur les espaces
+	out, err = exec.Command("ps", "-Ao", "pid=,command=").Output()
+	if err != nil {
+		return procs, nil
+	}
+	args := make(map[int][]string)
+	for _, l := range strings.Split(string(out), "\n") {
 		parts := strings.Fields(l)
 		if len(parts) < 2 {
 			continue
 		}
 		pid, _ := strconv.Atoi(parts[0])
-		name := parts[1]
-		procs = append(procs, Process{PID: pid, Name: name})
+		args[pid] = parts[1:]
+	}
+	for i := range procs {
+		procs[i].Args = args[procs[i].PID]
 	}
 	return procs, nil
 }
 
+// parseCPUTime lit une durée ps/tasklist : [[jj-]hh:]mm:ss[.cc].
+func parseCPUTime(s string) time.Duration {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		days, _ = strconv.Atoi(d)
+		s = rest
+	}
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		v, _ := strconv.ParseFloat(part, 64)
+		total = total*60 + v
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(total*float64(time.Second))
+}
+
+// parseWinMem lit la colonne mémoire de tasklist (« 12 345 K ») en octets.
+func parseWinMem(s string) uint64 {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	kb, _ := strconv.ParseUint(digits.String(), 10, 64)
+	return kb * 1024
+}
+
+// splitCommandLine découpe une ligne de commande Windows en respectant
+// les guillemets.
+func splitCommandLine(s string) []string {
+	var (
+		args     []string
+		cur      strings.Builder
+		inQuotes bool
+		started  bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
 func Filter(procs []Process, kw string) []Process {
 	kw = strings.ToLower(kw)
 	var out []Process
@@ -80,10 +240,21 @@ func Filter(procs []Process, kw string) []Process {
 	return out
 }
 
+// Find renvoie le processus pid de procs.
+func Find(procs []Process, pid int) (Process, bool) {
+	for _, p := range procs {
+		if p.PID == pid {
+			return p, true
+		}
+	}
+	return Process{}, false
+}
+
+// Kill termine le seul processus pid ; voir KillTree pour ses descendants.
 func Kill(pid int, force bool) error {
 	switch runtime.GOOS {
 	case "windows":
-		args := []string{"/PID", fmt.Sprint(pid), "/T"}
+		args := []string{"/PID", fmt.Sprint(pid)}
 		if force {
 			args = append(args, "/F")
 		}
@@ -94,6 +265,8 @@ func Kill(pid int, force bool) error {
 			signal = "-9"
 		}
 		return exec.Command("kill", signal, fmt.Sprint(pid)).Run()
+	case "linux":
+		return killLinux(pid, force)
 	default:
 		return errors.New("OS non supporté")
 	}
@@ -0,0 +1,193 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// procRoot est la racine procfs lue par List sous Linux.
+const procRoot = "/proc"
+
+// ListProcFS lit les processus d'une arborescence procfs (/proc ou une
+// copie factice sur disque) sans appeler ps.
+func ListProcFS(root string) ([]Process, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	sys := procSys{
+		bootTime: readBootTime(filepath.Join(root, "stat")),
+		pageSize: uint64(os.Getpagesize()),
+		users:    make(map[int]string),
+	}
+	var procs []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		p, err := sys.readProc(filepath.Join(root, e.Name()), pid)
+		if err != nil {
+			if unreadable(err) {
+				continue
+			}
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// unreadable : processus terminé pendant la lecture (ENOENT, ESRCH) ou
+// illisible pour l'utilisateur courant (EACCES, EPERM).
+func unreadable(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESRCH) ||
+		errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM)
+}
+
+// clockTicks vaut sysconf(_SC_CLK_TCK), fixé à 100 par le noyau Linux
+// sur toutes les architectures courantes.
+const clockTicks = 100
+
+// procSys regroupe les valeurs système communes à tous les processus.
+type procSys struct {
+	bootTime time.Time
+	pageSize uint64
+	users    map[int]string
+}
+
+// readProc lit /proc/<pid>/stat, status et cmdline.
+func (sys procSys) readProc(dir string, pid int) (Process, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Process{}, err
+	}
+	name, f, err := parseStat(stat)
+	if err != nil || len(f) < 22 {
+		return Process{}, fmt.Errorf("%s/stat : format invalide", dir)
+	}
+
+	// champs stat(5) numérotés à partir de l'état (champ 3)
+	field := func(n int) uint64 {
+		v, _ := strconv.ParseUint(f[n-3], 10, 64)
+		return v
+	}
+	p := Process{
+		PID:     pid,
+		PPID:    int(field(4)),
+		Name:    name,
+		UID:     -1,
+		State:   f[0],
+		CPUTime: time.Duration(field(14)+field(15)) * time.Second / clockTicks,
+		Threads: int(field(20)),
+		VSZ:     field(23),
+		RSS:     field(24) * sys.pageSize,
+	}
+	if !sys.bootTime.IsZero() {
+		p.StartTime = sys.bootTime.Add(time.Duration(field(22)) * time.Second / clockTicks)
+	}
+
+	// status et cmdline sont facultatifs : stat suffit
+	status, err := readStatus(filepath.Join(dir, "status"))
+	if err != nil && !unreadable(err) {
+		return Process{}, err
+	}
+	// status donne le nom complet (comm est tronqué à 15 caractères)
+	if status["Name"] != "" {
+		p.Name = status["Name"]
+	}
+	if uids := strings.Fields(status["Uid"]); len(uids) > 0 {
+		if uid, err := strconv.Atoi(uids[0]); err == nil {
+			p.UID = uid
+			p.User = sys.userName(uid)
+		}
+	}
+
+	args, err := readCmdline(filepath.Join(dir, "cmdline"))
+	if err != nil && !unreadable(err) {
+		return Process{}, err
+	}
+	p.Args = args
+	return p, nil
+}
+
+// userName résout un UID en nom d'utilisateur (avec cache).
+func (sys procSys) userName(uid int) string {
+	if name, ok := sys.users[uid]; ok {
+		return name
+	}
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	sys.users[uid] = name
+	return name
+}
+
+// readBootTime lit la ligne « btime » de /proc/stat.
+func readBootTime(path string) time.Time {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(l, "btime "); ok {
+			if sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseStat découpe /proc/<pid>/stat : le nom est entre parenthèses et
+// peut lui-même contenir des espaces ou des parenthèses.
+func parseStat(b []byte) (name string, fields []string, err error) {
+	s := string(b)
+	open := strings.IndexByte(s, '(')
+	end := strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return "", nil, errors.New("format stat invalide")
+	}
+	return s[open+1 : end], strings.Fields(s[end+1:]), nil
+}
+
+// readStatus lit les paires « Clé:\tvaleur » de /proc/<pid>/status.
+func readStatus(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	for _, l := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(l, ":")
+		if ok {
+			m[k] = strings.TrimSpace(v)
+		}
+	}
+	return m, nil
+}
+
+// readCmdline renvoie les arguments de /proc/<pid>/cmdline (séparés par NUL).
+// Les threads noyau n'en ont pas.
+func readCmdline(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := strings.TrimRight(string(b), "\x00")
+	if s == "" {
+		return nil, nil
+	}
+	return strings.Split(s, "\x00"), nil
+}
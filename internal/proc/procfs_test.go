@@ -0,0 +1,207 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		comm    string
+		fields  []string
+		wantErr bool
+	}{
+		{"simple", "12 (bash) S 1 12", "bash", []string{"S", "1", "12"}, false},
+		{"espaces", "7 (Web Content) R 3", "Web Content", []string{"R", "3"}, false},
+		{"parenthèses", "9 (a) b (c)) Z 2", "a) b (c)", []string{"Z", "2"}, false},
+		{"vide", "5 () S 1", "", []string{"S", "1"}, false},
+		{"sans nom", "5 S 1", "", nil, true},
+		{"mal fermé", "5 ) (x S", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comm, fields, err := parseStat([]byte(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, veut erreur %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if comm != tt.comm || !slices.Equal(fields, tt.fields) {
+				t.Errorf("parseStat(%q) = %q, %q ; veut %q, %q", tt.in, comm, fields, tt.comm, tt.fields)
+			}
+		})
+	}
+}
+
+// statLine construit une ligne /proc/<pid>/stat : utime et stime en
+// ticks, starttime en ticks depuis le démarrage, rss en pages.
+func statLine(pid, ppid int, comm, state string, utime, stime, threads, start, vsz, rss uint64) string {
+	return fmt.Sprintf("%d (%s) %s %d 0 0 0 -1 0 0 0 0 0 %d %d 0 0 20 0 %d 0 %d %d %d",
+		pid, comm, state, ppid, utime, stime, threads, start, vsz, rss)
+}
+
+// writeProc crée dir/<pid> avec les fichiers donnés (nom → contenu).
+func writeProc(t *testing.T, root string, pid int, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListProcFS(t *testing.T) {
+	root := t.TempDir()
+	const btime = 1700000000
+	if err := os.WriteFile(filepath.Join(root, "stat"), []byte("cpu 1 2 3\nbtime 1700000000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeProc(t, root, 1, map[string]string{
+		"stat":    statLine(1, 0, "init", "S", 150, 50, 1, 500, 4096, 3),
+		"status":  "Name:\tinit\nUid:\t0\t0\t0\t0\n",
+		"cmdline": "/sbin/init\x00splash\x00",
+	})
+	writeProc(t, root, 42, map[string]string{
+		"stat":   statLine(42, 1, "a very long nam", "R", 0, 0, 4, 0, 0, 0),
+		"status": "Name:\ta very long name indeed\nUid:\t1000\t1000\t1000\t1000\n",
+	})
+	writeProc(t, root, 2, map[string]string{
+		"stat": statLine(2, 0, "kthreadd", "S", 0, 0, 1, 0, 0, 0),
+	})
+	// ignorés : entrées non numériques et fichier numérique
+	if err := os.Mkdir(filepath.Join(root, "self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "99"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	procs, err := ListProcFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := uint64(os.Getpagesize())
+	boot := time.Unix(btime, 0)
+	tests := []struct {
+		pid  int
+		want Process
+	}{
+		{1, Process{
+			PID: 1, PPID: 0, Name: "init", UID: 0, State: "S",
+			CPUTime: 2 * time.Second, Threads: 1, VSZ: 4096, RSS: 3 * page,
+			StartTime: boot.Add(5 * time.Second), Args: []string{"/sbin/init", "splash"},
+		}},
+		{2, Process{
+			PID: 2, PPID: 0, Name: "kthreadd", UID: -1, State: "S",
+			Threads: 1, StartTime: boot,
+		}},
+		{42, Process{
+			PID: 42, PPID: 1, Name: "a very long name indeed", UID: 1000, State: "R",
+			Threads: 4, StartTime: boot,
+		}},
+	}
+	if len(procs) != len(tests) {
+		t.Fatalf("%d processus, veut %d : %+v", len(procs), len(tests), procs)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(tt.pid), func(t *testing.T) {
+			got := procs[i]
+			if got.UID >= 0 && got.User == "" {
+				t.Errorf("User vide pour l'UID %d", got.UID)
+			}
+			got.User = ""
+			if got.PID != tt.want.PID || got.PPID != tt.want.PPID || got.Name != tt.want.Name ||
+				got.UID != tt.want.UID || got.State != tt.want.State || got.CPUTime != tt.want.CPUTime ||
+				got.Threads != tt.want.Threads || got.VSZ != tt.want.VSZ || got.RSS != tt.want.RSS ||
+				!got.StartTime.Equal(tt.want.StartTime) || !slices.Equal(got.Args, tt.want.Args) {
+				t.Errorf("processus %d :\n got %+v\nwant %+v", tt.pid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListProcFSInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		stat string
+	}{
+		{"sans parenthèses", "3 x S 1"},
+		{"tronqué", "3 (x) S 1 0 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeProc(t, root, 3, map[string]string{"stat": tt.stat})
+			if _, err := ListProcFS(root); err == nil {
+				t.Error("pas d'erreur pour un stat invalide")
+			}
+		})
+	}
+	if _, err := ListProcFS(filepath.Join(t.TempDir(), "absent")); err == nil {
+		t.Error("pas d'erreur pour une racine absente")
+	}
+}
+
+func TestUnreadable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&fs.PathError{Op: "open", Path: "/proc/7/stat", Err: syscall.ENOENT}, true},
+		{&fs.PathError{Op: "read", Path: "/proc/7/cmdline", Err: syscall.ESRCH}, true},
+		{&fs.PathError{Op: "open", Path: "/proc/7/stat", Err: syscall.EACCES}, true},
+		{&fs.PathError{Op: "open", Path: "/proc/7/status", Err: syscall.EPERM}, true},
+		{fmt.Errorf("lecture : %w", syscall.ESRCH), true},
+		{&fs.PathError{Op: "read", Path: "/proc/7/stat", Err: syscall.EIO}, false},
+		{errors.New("format invalide"), false},
+	}
+	for _, tt := range tests {
+		if got := unreadable(tt.err); got != tt.want {
+			t.Errorf("unreadable(%v) = %v, veut %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestListProcFSUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root lit les fichiers sans droits")
+	}
+	root := t.TempDir()
+	writeProc(t, root, 1, map[string]string{"stat": statLine(1, 0, "init", "S", 0, 0, 1, 0, 0, 0)})
+	// processus d'un autre utilisateur : stat, puis cmdline seul, illisibles
+	writeProc(t, root, 5, map[string]string{"stat": statLine(5, 1, "x", "S", 0, 0, 1, 0, 0, 0)})
+	writeProc(t, root, 6, map[string]string{
+		"stat":    statLine(6, 1, "y", "S", 0, 0, 1, 0, 0, 0),
+		"cmdline": "y\x00",
+	})
+	for _, f := range []string{"5/stat", "6/cmdline"} {
+		if err := os.Chmod(filepath.Join(root, f), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	procs, err := ListProcFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pids []int
+	for _, p := range procs {
+		pids = append(pids, p.PID)
+	}
+	if !slices.Equal(pids, []int{1, 6}) {
+		t.Errorf("PID %v, veut [1 6]", pids)
+	}
+}
@@ -0,0 +1,117 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Children indexe les processus par PID parent, triés par PID.
+func Children(procs []Process) map[int][]Process {
+	kids := make(map[int][]Process)
+	for _, p := range procs {
+		if p.PPID == p.PID {
+			continue
+		}
+		kids[p.PPID] = append(kids[p.PPID], p)
+	}
+	for _, list := range kids {
+		sort.Slice(list, func(i, j int) bool { return list[i].PID < list[j].PID })
+	}
+	return kids
+}
+
+// Roots renvoie les processus dont le parent est absent de procs.
+func Roots(procs []Process) []Process {
+	known := make(map[int]bool, len(procs))
+	for _, p := range procs {
+		known[p.PID] = true
+	}
+	var roots []Process
+	for _, p := range procs {
+		if !known[p.PPID] || p.PPID == p.PID {
+			roots = append(roots, p)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].PID < roots[j].PID })
+	return roots
+}
+
+// Subtree renvoie pid et tous ses descendants, enfants d'abord et parent
+// en dernier : c'est l'ordre dans lequel KillTree les termine.
+func Subtree(procs []Process, pid int) []Process {
+	root, ok := Find(procs, pid)
+	if !ok {
+		return nil
+	}
+	kids := Children(procs)
+	seen := make(map[int]bool)
+	var out []Process
+	var walk func(p Process)
+	walk = func(p Process) {
+		if seen[p.PID] {
+			return
+		}
+		seen[p.PID] = true
+		for _, c := range kids[p.PID] {
+			walk(c)
+		}
+		out = append(out, p)
+	}
+	walk(root)
+	return out
+}
+
+// WriteTree dessine l'arbre des processus à partir de root
+// (0 = toutes les racines).
+func WriteTree(w io.Writer, procs []Process, root int) {
+	kids := Children(procs)
+	seen := make(map[int]bool)
+
+	var walk func(p Process, prefix string, last bool, top bool)
+	walk = func(p Process, prefix string, last bool, top bool) {
+		if seen[p.PID] {
+			return
+		}
+		seen[p.PID] = true
+		branch, next := "├── ", "│   "
+		if last {
+			branch, next = "└── ", "    "
+		}
+		if top {
+			branch, next = "", ""
+		}
+		fmt.Fprintf(w, "%s%s%d %s\n", prefix, branch, p.PID, p.Name)
+		children := kids[p.PID]
+		for i, c := range children {
+			walk(c, prefix+next, i == len(children)-1, false)
+		}
+	}
+
+	if root > 0 {
+		if p, ok := Find(procs, root); ok {
+			walk(p, "", true, true)
+		}
+		return
+	}
+	for _, p := range Roots(procs) {
+		walk(p, "", true, true)
+	}
+}
+
+// KillTree termine pid et ses descendants, enfants d'abord, de la même
+// manière sur tous les OS. Les processus déjà terminés sont ignorés.
+func KillTree(procs []Process, pid int, force bool) error {
+	targets := Subtree(procs, pid)
+	if len(targets) == 0 {
+		return fmt.Errorf("PID %d introuvable", pid)
+	}
+	var errs []error
+	for _, p := range targets {
+		if err := Kill(p.PID, force); err != nil && !isGone(err) {
+			errs = append(errs, fmt.Errorf("PID %d : %w", p.PID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format est le format de sortie des commandes.
+type Format string
+
+const (
+	Text  Format = "text"
+	JSON  Format = "json"
+	JSONL Format = "jsonl"
+	CSV   Format = "csv"
+)
+
+// ParseFormat valide un nom de format (vide = texte).
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return Text, nil
+	case Text, JSON, JSONL, CSV:
+		return f, nil
+	case "ndjson":
+		return JSONL, nil
+	default:
+		return "", fmt.Errorf("format inconnu %q (text, json, jsonl, csv)", s)
+	}
+}
+
+// Write encode v (struct ou slice de structs) en JSON, JSON Lines ou CSV.
+// Le format texte est propre à chaque commande et n'est pas géré ici.
+func Write(w io.Writer, f Format, v any) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case JSONL:
+		enc := json.NewEncoder(w)
+		for _, item := range items(v) {
+			if err := enc.Encode(item.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		return writeCSV(w, v)
+	default:
+		return fmt.Errorf("format %q non géré par render.Write", f)
+	}
+}
+
+// items renvoie les éléments d'une slice, ou v seul.
+func items(v any) []reflect.Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []reflect.Value{rv}
+	}
+	out := make([]reflect.Value, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i)
+	}
+	return out
+}
+
+type column struct {
+	name  string
+	index []int
+}
+
+// columns liste les champs exportés d'une struct, nommés d'après leur tag json.
+func columns(t reflect.Type) []column {
+	var cols []column
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		cols = append(cols, column{name: name, index: f.Index})
+	}
+	return cols
+}
+
+func writeCSV(w io.Writer, v any) error {
+	rows := items(v)
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("csv : type %s non tabulaire", t)
+	}
+
+	cols := columns(t)
+	cw := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for row.Kind() == reflect.Pointer {
+			row = row.Elem()
+		}
+		rec := make([]string, len(cols))
+		for i, c := range cols {
+			rec[i] = cell(row.FieldByIndex(c.index))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cell convertit une valeur en texte CSV.
+func cell(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(time.RFC3339)
+	case time.Duration:
+		return strconv.FormatFloat(x.Seconds(), 'f', -1, 64)
+	case fmt.Stringer:
+		return x.String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', 2, 64)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			return strings.Join(v.Interface().([]string), " ")
+		}
+	}
+	b, _ := json.Marshal(v.Interface())
+	return string(b)
+}
@@ -1,21 +1,379 @@
 package secure
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-func Log(outDir, action, details string) {
-	path := filepath.Join(outDir, "audit.log")
-	line := fmt.Sprintf("%s | %-6s | %s\n",
-		time.Now().Format("2006-01-02 15:04:05"), action, details)
-	_ = os.MkdirAll(outDir, 0o755)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+// Chaque ligne de audit.log est une Entry JSON. Prev est le SHA-256 de la
+// ligne précédente (brute, sans le saut de ligne) : modifier, supprimer ou
+// insérer une ligne casse la chaîne. MAC est un HMAC-SHA256 de l'entrée
+// (MAC vide) calculé avec la clé locale, si elle est configurée.
+//
+// Les lignes texte « date | action | détails » des versions précédentes
+// sont acceptées en tête de fichier ; la première entrée JSON les chaîne.
+// Avec une clé, rien n'est accepté sans signature : ni ligne ancienne, ni
+// entrée sans MAC (sinon il suffirait de retirer les MAC et de recalculer
+// la chaîne).
+//
+// audit.head garde la dernière entrée écrite (numéro et empreinte, signés
+// avec la clé) : il est mis à jour sous le même verrou que le journal et
+// permet de détecter la suppression des dernières lignes.
+
+// AuditKeyEnv contient la clé HMAC ; AuditKeyFileEnv, un fichier qui la contient.
+const (
+	AuditKeyEnv     = "FILEOPS_AUDIT_KEY"
+	AuditKeyFileEnv = "FILEOPS_AUDIT_KEY_FILE"
+)
+
+// Entry est un enregistrement du journal d'audit.
+type Entry struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Details string    `json:"details"`
+	User    string    `json:"user,omitempty"`
+	PID     int       `json:"pid"`
+	Prev    string    `json:"prev"`
+	MAC     string    `json:"mac,omitempty"`
+}
+
+// auditHead est le contenu de audit.head.
+type auditHead struct {
+	Seq  int    `json:"seq"`
+	Hash string `json:"hash"`
+	MAC  string `json:"mac,omitempty"`
+}
+
+var auditMu sync.Mutex
+
+// AuditPath renvoie le chemin du journal d'audit.
+func AuditPath(outDir string) string {
+	return filepath.Join(outDir, "audit.log")
+}
+
+func headPath(outDir string) string {
+	return filepath.Join(outDir, "audit.head")
+}
+
+// Log ajoute une entrée chaînée au journal ; toute erreur d'écriture est
+// renvoyée à l'appelant.
+func Log(outDir, action, details string) error {
+	key, err := auditKey()
 	if err != nil {
-		return
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+	f, err := os.OpenFile(AuditPath(outDir), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("audit : %w", err)
 	}
 	defer f.Close()
-	_, _ = f.WriteString(line)
+	// verrou entre processus : la lecture de la dernière ligne et l'ajout
+	// doivent être atomiques
+	if err := flockWait(f); err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+
+	last, err := lastLine(f)
+	if err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+	e := Entry{
+		Seq:     1,
+		Time:    time.Now(),
+		Action:  action,
+		Details: details,
+		PID:     os.Getpid(),
+		Prev:    hashLine(last),
+	}
+	var prev Entry
+	if json.Unmarshal(last, &prev) == nil && prev.Seq > 0 {
+		e.Seq = prev.Seq + 1
+	}
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	}
+	if key != nil {
+		e.MAC = entryMAC(e, key)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+	// toujours sous le flock du journal
+	if err := writeHead(outDir, auditHead{Seq: e.Seq, Hash: hashLine(line)}, key); err != nil {
+		return fmt.Errorf("audit : %w", err)
+	}
+	return nil
+}
+
+// writeHead remplace audit.head de façon atomique.
+func writeHead(outDir string, h auditHead, key []byte) error {
+	if key != nil {
+		h.MAC = headMAC(h, key)
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(outDir, ".audit.head-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), headPath(outDir))
+}
+
+// readHead lit audit.head (os.ErrNotExist s'il est absent).
+func readHead(outDir string) (auditHead, error) {
+	var h auditHead
+	b, err := os.ReadFile(headPath(outDir))
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(b, &h); err != nil || h.Seq == 0 {
+		return h, fmt.Errorf("%s illisible", headPath(outDir))
+	}
+	return h, nil
+}
+
+// VerifyReport est le résultat de Verify.
+type VerifyReport struct {
+	Path     string `json:"path"`
+	Entries  int    `json:"entries"`
+	Legacy   int    `json:"legacy"`
+	Unsigned int    `json:"unsigned"` // entrées sans MAC (refusées avec une clé)
+	Signed   bool   `json:"signed"`
+	Head     bool   `json:"head"` // fin du journal vérifiée avec audit.head
+	OK       bool   `json:"ok"`
+	Line     int    `json:"line,omitempty"`
+	Seq      int    `json:"seq,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Verify parcourt la chaîne et s'arrête à la première entrée altérée,
+// manquante ou mal signée, puis compare la dernière entrée à audit.head.
+// Une erreur n'est renvoyée que si le journal est illisible.
+func Verify(outDir string) (VerifyReport, error) {
+	rep := VerifyReport{Path: AuditPath(outDir)}
+	key, err := auditKey()
+	if err != nil {
+		return rep, err
+	}
+	rep.Signed = key != nil
+
+	f, err := os.Open(rep.Path)
+	if err != nil {
+		return rep, err
+	}
+	defer f.Close()
+	// pas de lecture pendant qu'un Log ajoute une entrée et met à jour l'en-tête
+	if err := flockWait(f); err != nil {
+		return rep, err
+	}
+
+	var (
+		prevLine []byte
+		prevSeq  int
+		lineNo   int
+	)
+	fail := func(seq int, format string, args ...any) (VerifyReport, error) {
+		rep.Line, rep.Seq, rep.Reason = lineNo, seq, fmt.Sprintf(format, args...)
+		return rep, nil
+	}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		lineNo++
+		line := bytes.Clone(sc.Bytes())
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil || e.Seq == 0 {
+			if prevSeq > 0 {
+				return fail(prevSeq+1, "ligne non chaînée après l'entrée %d", prevSeq)
+			}
+			if key != nil {
+				return fail(0, "ligne non signée (format ancien) alors qu'une clé est configurée")
+			}
+			rep.Legacy++
+			prevLine = line
+			continue
+		}
+
+		switch {
+		case e.Seq != prevSeq+1:
+			return fail(e.Seq, "entrée %d attendue, %d trouvée (entrée manquante ou réordonnée)", prevSeq+1, e.Seq)
+		case e.Prev != hashLine(prevLine):
+			return fail(e.Seq, "empreinte de l'entrée précédente invalide (ligne %d modifiée ou supprimée)", lineNo-1)
+		case key != nil && e.MAC != "" && !hmac.Equal([]byte(e.MAC), []byte(entryMAC(e, key))):
+			return fail(e.Seq, "HMAC invalide : entrée modifiée")
+		case key != nil && e.MAC == "":
+			return fail(e.Seq, "HMAC absent alors qu'une clé est configurée")
+		}
+		if e.MAC == "" {
+			rep.Unsigned++
+		}
+		prevSeq, prevLine = e.Seq, line
+		rep.Entries++
+	}
+	if err := sc.Err(); err != nil {
+		return rep, err
+	}
+
+	head, err := readHead(outDir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// journal écrit par une version sans en-tête : accepté sans clé
+		if key != nil && prevSeq > 0 {
+			return fail(prevSeq, "audit.head absent alors qu'une clé est configurée")
+		}
+		rep.OK = true
+		return rep, nil
+	case err != nil:
+		return fail(prevSeq, "%v", err)
+	case key != nil && !hmac.Equal([]byte(head.MAC), []byte(headMAC(head, key))):
+		return fail(head.Seq, "HMAC de audit.head invalide")
+	case head.Seq > prevSeq:
+		return fail(prevSeq+1, "journal tronqué : dernière entrée %d, audit.head en annonce %d", prevSeq, head.Seq)
+	case head.Seq < prevSeq || head.Hash != hashLine(prevLine):
+		return fail(prevSeq, "dernière entrée différente de celle de audit.head (entrée %d)", head.Seq)
+	}
+	rep.Head = true
+	rep.OK = true
+	return rep, nil
+}
+
+// WriteText affiche le rapport de vérification.
+func (r VerifyReport) WriteText(w io.Writer) {
+	if !r.OK {
+		fmt.Fprintf(w, "%s : ALTÉRÉ à la ligne %d (entrée %d) : %s\n", r.Path, r.Line, r.Seq, r.Reason)
+		return
+	}
+	fmt.Fprintf(w, "%s : chaîne intacte, %d entrées", r.Path, r.Entries)
+	if r.Legacy > 0 {
+		fmt.Fprintf(w, " (+ %d lignes anciennes non chaînées)", r.Legacy)
+	}
+	if !r.Head {
+		fmt.Fprint(w, ", fin non vérifiée (pas de audit.head)")
+	}
+	if r.Signed {
+		fmt.Fprint(w, ", HMAC vérifiés")
+	} else {
+		fmt.Fprintf(w, ", HMAC non vérifiés (pas de clé ; %d entrées non signées)", r.Unsigned)
+	}
+	fmt.Fprintln(w)
+}
+
+// hashLine renvoie le SHA-256 hexadécimal d'une ligne (64 zéros pour la première entrée).
+func hashLine(line []byte) string {
+	if line == nil {
+		return strings.Repeat("0", 64)
+	}
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+func entryMAC(e Entry, key []byte) string {
+	e.MAC = ""
+	b, _ := json.Marshal(e)
+	m := hmac.New(sha256.New, key)
+	m.Write(b)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func headMAC(h auditHead, key []byte) string {
+	m := hmac.New(sha256.New, key)
+	fmt.Fprintf(m, "%d %s", h.Seq, h.Hash)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// auditKey lit la clé HMAC (nil si aucune n'est configurée).
+func auditKey() ([]byte, error) {
+	if k := os.Getenv(AuditKeyEnv); k != "" {
+		return []byte(k), nil
+	}
+	path := os.Getenv(AuditKeyFileEnv)
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("clé d'audit : %w", err)
+	}
+	k := bytes.TrimSpace(b)
+	if len(k) == 0 {
+		return nil, errors.New("clé d'audit vide : " + path)
+	}
+	return k, nil
+}
+
+// lastLine renvoie la dernière ligne non vide de f (nil si f est vide).
+func lastLine(f *os.File) ([]byte, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	const chunk = 4096
+	var buf []byte
+	for off := size; off > 0; {
+		n := int64(chunk)
+		if off < n {
+			n = off
+		}
+		off -= n
+		b := make([]byte, n)
+		if _, err := f.ReadAt(b, off); err != nil {
+			return nil, err
+		}
+		buf = append(b, buf...)
+		trimmed := bytes.TrimRight(buf, "\r\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if off == 0 && len(trimmed) > 0 {
+			return trimmed, nil
+		}
+	}
+	return nil, nil
 }
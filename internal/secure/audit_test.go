@@ -0,0 +1,142 @@
+package secure
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// writeAudit écrit n entrées puis renvoie les lignes du journal.
+func writeAudit(t *testing.T, dir string, n int) [][]byte {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := Log(dir, "TEST", strings.Repeat("x", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(AuditPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(bytes.TrimRight(b, "\n"), []byte("\n"))
+}
+
+func rewrite(t *testing.T, path string, lines ...[]byte) {
+	t.Helper()
+	if err := os.WriteFile(path, bytes.Join(lines, nil), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		tamper func(t *testing.T, dir string, lines [][]byte)
+		ok     bool
+		reason string
+	}{
+		{"intact", "", nil, true, ""},
+		{"intact signé", "secret", nil, true, ""},
+		{"ligne modifiée", "", func(t *testing.T, dir string, l [][]byte) {
+			l[1] = bytes.Replace(l[1], []byte("TEST"), []byte("TOST"), 1)
+			rewrite(t, AuditPath(dir), l...)
+		}, false, "empreinte de l'entrée précédente invalide"},
+		{"ligne supprimée", "", func(t *testing.T, dir string, l [][]byte) {
+			rewrite(t, AuditPath(dir), l[0], l[2], l[3])
+		}, false, "entrée 2 attendue, 3 trouvée"},
+		{"fin tronquée", "", func(t *testing.T, dir string, l [][]byte) {
+			rewrite(t, AuditPath(dir), l[0], l[1])
+		}, false, "journal tronqué : dernière entrée 2, audit.head en annonce 4"},
+		{"journal vidé", "secret", func(t *testing.T, dir string, l [][]byte) {
+			rewrite(t, AuditPath(dir))
+		}, false, "journal tronqué"},
+		{"en-tête supprimé sans clé", "", func(t *testing.T, dir string, l [][]byte) {
+			os.Remove(headPath(dir))
+		}, true, ""},
+		{"en-tête supprimé avec clé", "secret", func(t *testing.T, dir string, l [][]byte) {
+			os.Remove(headPath(dir))
+		}, false, "audit.head absent"},
+		{"en-tête reculé", "secret", func(t *testing.T, dir string, l [][]byte) {
+			rewrite(t, AuditPath(dir), l[0], l[1])
+			if err := writeHead(dir, auditHead{Seq: 2, Hash: hashLine(bytes.TrimRight(l[1], "\n"))}, []byte("autre")); err != nil {
+				t.Fatal(err)
+			}
+		}, false, "HMAC de audit.head invalide"},
+		{"entrée sans HMAC", "secret", func(t *testing.T, dir string, l [][]byte) {
+			t.Setenv(AuditKeyEnv, "")
+			Log(dir, "SANS CLÉ", "")
+			t.Setenv(AuditKeyEnv, "secret")
+		}, false, "HMAC absent"},
+		{"mauvaise clé", "secret", func(t *testing.T, dir string, l [][]byte) {
+			t.Setenv(AuditKeyEnv, "autre")
+		}, false, "HMAC invalide"},
+		{"ancien format avec clé", "secret", func(t *testing.T, dir string, l [][]byte) {
+			rewrite(t, AuditPath(dir), append([]byte("2024-01-01 | LOCK | x\n"), bytes.Join(l, nil)...))
+		}, false, "ligne non signée"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(AuditKeyEnv, tt.key)
+			t.Setenv(AuditKeyFileEnv, "")
+			dir := t.TempDir()
+			lines := writeAudit(t, dir, 4)
+			if tt.tamper != nil {
+				tt.tamper(t, dir, lines)
+			}
+			rep, err := Verify(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rep.OK != tt.ok || !strings.Contains(rep.Reason, tt.reason) {
+				t.Errorf("Verify = %+v, veut ok=%v %q", rep, tt.ok, tt.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	t.Setenv(AuditKeyEnv, "")
+	t.Setenv(AuditKeyFileEnv, "")
+	dir := t.TempDir()
+	rewrite(t, AuditPath(dir), []byte("2024-01-01 | LOCK | a\n2024-01-02 | UNLOCK | a\n"))
+	if err := Log(dir, "TEST", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.OK || rep.Legacy != 2 || rep.Entries != 1 || rep.Unsigned != 1 || !rep.Head {
+		t.Errorf("Verify = %+v", rep)
+	}
+}
+
+func TestAuditKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(AuditKeyEnv, "")
+	tests := []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"secret\n", "secret", false},
+		{"  \n", "", true},
+	}
+	for _, tt := range tests {
+		path := dir + "/cle"
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(AuditKeyFileEnv, path)
+		k, err := auditKey()
+		if (err != nil) != tt.wantErr || string(k) != tt.want {
+			t.Errorf("auditKey(%q) = %q, %v", tt.content, k, err)
+		}
+	}
+	t.Setenv(AuditKeyFileEnv, dir+"/absente")
+	if _, err := auditKey(); err == nil {
+		t.Error("pas d'erreur pour un fichier de clé absent")
+	}
+}
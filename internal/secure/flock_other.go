@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || freebsd)
+
+package secure
+
+import "os"
+
+// flock : pas de verrou consultatif ici, seul le fichier .lock protège.
+func flock(f *os.File) error {
+	return nil
+}
+
+// processAlive : sous Windows, FindProcess échoue si pid n'existe pas.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+func flockWait(f *os.File) error {
+	return nil
+}
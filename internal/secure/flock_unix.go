@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd
+
+package secure
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// flock prend un verrou exclusif non bloquant sur f (flock(2)).
+func flock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+// processAlive teste l'existence de pid avec le signal 0.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// flockWait attend le verrou exclusif sur f ; il est libéré à la fermeture.
+func flockWait(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
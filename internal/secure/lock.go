@@ -0,0 +1,301 @@
+package secure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockRecord est le contenu d'un fichier .lock : qui détient le verrou.
+type LockRecord struct {
+	Target string    `json:"target"`
+	PID    int       `json:"pid"`
+	Host   string    `json:"host"`
+	User   string    `json:"user"`
+	Time   time.Time `json:"time"`
+}
+
+func (r LockRecord) String() string {
+	if r.PID == 0 {
+		return "propriétaire inconnu (ancien format)"
+	}
+	return fmt.Sprintf("PID %d (%s@%s) depuis %s",
+		r.PID, r.User, r.Host, r.Time.Format("2006-01-02 15:04:05"))
+}
+
+// ErrLocked : le verrou appartient à un processus vivant (ou à un autre hôte).
+var ErrLocked = errors.New("déjà verrouillé")
+
+// ErrStale : le propriétaire du verrou n'existe plus, seule une levée
+// forcée (Break) peut le supprimer.
+var ErrStale = errors.New("verrou périmé")
+
+// LockError précise ErrLocked ou ErrStale avec le verrou en cause.
+type LockError struct {
+	Err    error
+	Path   string
+	Record LockRecord
+}
+
+func (e *LockError) Error() string {
+	msg := fmt.Sprintf("%v (%s) : %s", e.Err, e.Path, e.Record)
+	if errors.Is(e.Err, ErrStale) {
+		msg += ", levée forcée nécessaire"
+	}
+	return msg
+}
+
+func (e *LockError) Unwrap() error { return e.Err }
+
+// LockOptions règle Lock ; OwnerPID vaut par défaut le PID courant.
+// La commande « fileops secure lock » indique le PID du shell appelant
+// pour que le verrou survive à sa propre fin.
+type LockOptions struct {
+	OwnerPID int
+}
+
+// verrous flock détenus par ce processus, par chemin de .lock
+var (
+	heldMu sync.Mutex
+	held   = map[string]*heldLock{}
+)
+
+type heldLock struct {
+	lock, target *os.File
+}
+
+// LockPath renvoie le fichier .lock associé à target.
+func LockPath(target, outDir string) string {
+	return filepath.Join(outDir, filepath.Base(target)+".lock")
+}
+
+func Lock(target, outDir string) (string, error) {
+	return LockWith(target, outDir, LockOptions{})
+}
+
+// LockWith pose un verrou consultatif flock sur le fichier .lock et sur
+// target, puis y écrit le propriétaire. Les flock sont gardés jusqu'à
+// Unlock ou la fin du processus.
+func LockWith(target, outDir string, opt LockOptions) (string, error) {
+	lock := LockPath(target, outDir)
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return lock, err
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return lock, err
+	}
+
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if _, ok := held[lock]; ok {
+		rec, _ := readRecord(lock)
+		return lock, &LockError{Err: ErrLocked, Path: lock, Record: rec}
+	}
+
+	lf, err := openLocked(lock)
+	if err != nil {
+		return lock, err
+	}
+	if rec, err := readRecord(lock); err == nil {
+		if state := recordState(rec); state != nil {
+			lf.Close()
+			return lock, &LockError{Err: state, Path: lock, Record: rec}
+		}
+	}
+
+	// verrou sur la cible elle-même, pour les outils qui utilisent flock
+	var tf *os.File
+	if f, err := os.Open(abs); err == nil {
+		if err := flock(f); err != nil {
+			f.Close()
+			lf.Close()
+			return lock, fmt.Errorf("%s verrouillé par un autre processus : %w", target, err)
+		}
+		tf = f
+	}
+
+	rec := newRecord(abs, opt.OwnerPID)
+	if err := writeRecord(lf, rec); err != nil {
+		closeAll(lf, tf)
+		return lock, err
+	}
+	held[lock] = &heldLock{lock: lf, target: tf}
+	return lock, nil
+}
+
+// Unlock supprime un verrou posé par ce processus (ou par le shell qui
+// l'a lancé). Un verrou d'un autre propriétaire exige Break.
+func Unlock(target, outDir string) (string, error) {
+	lock := LockPath(target, outDir)
+
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if h, ok := held[lock]; ok {
+		delete(held, lock)
+		err := os.Remove(lock)
+		closeAll(h.lock, h.target)
+		return lock, err
+	}
+
+	rec, err := readRecord(lock)
+	if err != nil {
+		return lock, err
+	}
+	if !ownedByUs(rec) {
+		if state := recordState(rec); state != nil {
+			return lock, &LockError{Err: state, Path: lock, Record: rec}
+		}
+	}
+	return lock, os.Remove(lock)
+}
+
+// Break supprime un verrou quel que soit son propriétaire et trace
+// l'opération (avec l'ancien propriétaire) dans l'audit.
+func Break(target, outDir string) (LockRecord, error) {
+	lock := LockPath(target, outDir)
+	rec, err := readRecord(lock)
+	if err != nil {
+		return rec, err
+	}
+
+	heldMu.Lock()
+	if h, ok := held[lock]; ok {
+		delete(held, lock)
+		closeAll(h.lock, h.target)
+	}
+	heldMu.Unlock()
+
+	if err := os.Remove(lock); err != nil {
+		return rec, err
+	}
+	return rec, Log(outDir, "LOCK BREAK", fmt.Sprintf("%s, ancien propriétaire : %s", lock, rec))
+}
+
+// LockStatus décrit l'état d'un verrou.
+type LockStatus struct {
+	Path   string     `json:"path"`
+	Exists bool       `json:"exists"`
+	Stale  bool       `json:"stale"`
+	Record LockRecord `json:"record"`
+}
+
+// Status lit le verrou de target sans le modifier.
+func Status(target, outDir string) (LockStatus, error) {
+	st := LockStatus{Path: LockPath(target, outDir)}
+	rec, err := readRecord(st.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	st.Exists, st.Record = true, rec
+	st.Stale = errors.Is(recordState(rec), ErrStale)
+	return st, nil
+}
+
+// recordState renvoie ErrLocked, ErrStale, ou nil si rec est à nous.
+func recordState(rec LockRecord) error {
+	host, _ := os.Hostname()
+	switch {
+	case rec.PID == 0:
+		// ancien format (simple date) : aucun propriétaire vérifiable
+		return ErrStale
+	case rec.Host != host:
+		return ErrLocked
+	case rec.PID == os.Getpid():
+		return nil
+	case processAlive(rec.PID):
+		return ErrLocked
+	default:
+		return ErrStale
+	}
+}
+
+// ownedByUs : verrou posé par ce processus ou par son parent (CLI).
+func ownedByUs(rec LockRecord) bool {
+	host, _ := os.Hostname()
+	return rec.Host == host && (rec.PID == os.Getpid() || rec.PID == os.Getppid())
+}
+
+func newRecord(target string, pid int) LockRecord {
+	if pid <= 0 {
+		pid = os.Getpid()
+	}
+	rec := LockRecord{Target: target, PID: pid, Time: time.Now()}
+	rec.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		rec.User = u.Username
+	}
+	return rec
+}
+
+// openLocked ouvre path et prend son flock exclusif, en recommençant si
+// le fichier a été supprimé puis recréé entre l'ouverture et le flock.
+func openLocked(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		if err := flock(f); err != nil {
+			f.Close()
+			rec, _ := readRecord(path)
+			return nil, &LockError{Err: ErrLocked, Path: path, Record: rec}
+		}
+		fi, err1 := f.Stat()
+		cur, err2 := os.Stat(path)
+		if err1 == nil && err2 == nil && os.SameFile(fi, cur) {
+			return f, nil
+		}
+		f.Close()
+	}
+}
+
+// readRecord lit un .lock ; un fichier vide (en cours de création) est
+// une erreur os.ErrNotExist.
+func readRecord(path string) (LockRecord, error) {
+	var rec LockRecord
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return rec, os.ErrNotExist
+	}
+	if err := json.Unmarshal([]byte(s), &rec); err != nil {
+		// ancien format : date RFC 3339 seule
+		rec.Time, _ = time.Parse(time.RFC3339, s)
+	}
+	return rec, nil
+}
+
+func writeRecord(f *os.File, rec LockRecord) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(append(b, '\n'), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func closeAll(files ...*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package secure
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pid qui ne peut pas exister (au-delà de pid_max)
+const deadPID = 1 << 30
+
+func writeLockFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recordJSON(t *testing.T, rec LockRecord) string {
+	t.Helper()
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLockRecordString(t *testing.T) {
+	tests := []struct {
+		rec  LockRecord
+		want string
+	}{
+		{LockRecord{}, "propriétaire inconnu (ancien format)"},
+		{LockRecord{PID: 42, User: "bob", Host: "srv", Time: time.Date(2026, 10, 18, 9, 5, 0, 0, time.UTC)},
+			"PID 42 (bob@srv) depuis 2026-10-18 09:05:00"},
+	}
+	for _, tt := range tests {
+		if got := tt.rec.String(); got != tt.want {
+			t.Errorf("String() = %q, veut %q", got, tt.want)
+		}
+	}
+}
+
+func TestReadRecord(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := os.Hostname()
+	tests := []struct {
+		name    string
+		content string
+		pid     int
+		wantErr error
+		state   error
+	}{
+		{"vide", "  \n", 0, os.ErrNotExist, nil},
+		{"ancien format", "2026-10-18T09:00:00Z\n", 0, nil, ErrStale},
+		{"à nous", recordJSON(t, LockRecord{PID: os.Getpid(), Host: host}), os.Getpid(), nil, nil},
+		{"processus vivant", recordJSON(t, LockRecord{PID: os.Getppid(), Host: host}), os.Getppid(), nil, ErrLocked},
+		{"processus mort", recordJSON(t, LockRecord{PID: deadPID, Host: host}), deadPID, nil, ErrStale},
+		{"autre hôte", recordJSON(t, LockRecord{PID: deadPID, Host: host + "-autre"}), deadPID, nil, ErrLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "x.lock")
+			writeLockFile(t, path, tt.content)
+			rec, err := readRecord(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readRecord : err = %v, veut %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if rec.PID != tt.pid {
+				t.Errorf("PID %d, veut %d", rec.PID, tt.pid)
+			}
+			if got := recordState(rec); !errors.Is(got, tt.state) || (got == nil) != (tt.state == nil) {
+				t.Errorf("recordState = %v, veut %v", got, tt.state)
+			}
+		})
+	}
+}
+
+func TestLockUnlock(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.txt")
+	writeLockFile(t, target, "x")
+	out := filepath.Join(dir, "out")
+
+	lock, err := Lock(target, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock != filepath.Join(out, "data.txt.lock") {
+		t.Errorf("chemin du verrou %s", lock)
+	}
+	st, err := Status(target, out)
+	if err != nil || !st.Exists || st.Stale || st.Record.PID != os.Getpid() {
+		t.Errorf("Status = %+v, %v", st, err)
+	}
+
+	// second verrou refusé, avec le propriétaire dans l'erreur
+	_, err = Lock(target, out)
+	var le *LockError
+	if !errors.As(err, &le) || !errors.Is(err, ErrLocked) || le.Record.PID != os.Getpid() {
+		t.Errorf("second Lock : err = %v, veut ErrLocked", err)
+	}
+
+	if _, err := Unlock(target, out); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := Status(target, out); st.Exists {
+		t.Errorf("verrou toujours présent : %+v", st)
+	}
+	if _, err := Unlock(target, out); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Unlock sans verrou : err = %v", err)
+	}
+
+	// reverrouillable après Unlock
+	if _, err := Lock(target, out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unlock(target, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.txt")
+	host, _ := os.Hostname()
+	lock := LockPath(target, dir)
+	writeLockFile(t, lock, recordJSON(t, LockRecord{PID: deadPID, Host: host, User: "bob"}))
+
+	st, err := Status(target, dir)
+	if err != nil || !st.Exists || !st.Stale {
+		t.Errorf("Status = %+v, %v ; veut périmé", st, err)
+	}
+
+	// ni Lock ni Unlock ne reprennent un verrou périmé
+	for name, f := range map[string]func() error{
+		"Lock":   func() error { _, err := Lock(target, dir); return err },
+		"Unlock": func() error { _, err := Unlock(target, dir); return err },
+	} {
+		err := f()
+		if !errors.Is(err, ErrStale) || !strings.Contains(err.Error(), "levée forcée nécessaire") {
+			t.Errorf("%s : err = %v, veut ErrStale", name, err)
+		}
+	}
+
+	rec, err := Break(target, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.PID != deadPID {
+		t.Errorf("Break : ancien propriétaire %+v", rec)
+	}
+	if _, err := os.Stat(lock); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("verrou non supprimé : %v", err)
+	}
+	b, err := os.ReadFile(AuditPath(dir))
+	if err != nil || !strings.Contains(string(b), "LOCK BREAK") || !strings.Contains(string(b), "PID 1073741824") {
+		t.Errorf("audit : %q, %v", b, err)
+	}
+
+	if _, err := Lock(target, dir); err != nil {
+		t.Fatalf("Lock après Break : %v", err)
+	}
+	if _, err := Unlock(target, dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreakHeld(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.txt")
+	if _, err := Lock(target, dir); err != nil {
+		t.Fatal(err)
+	}
+	// Break libère aussi le flock détenu par ce processus
+	if _, err := Break(target, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Lock(target, dir); err != nil {
+		t.Fatalf("Lock après Break : %v", err)
+	}
+	if _, err := Unlock(target, dir); err != nil {
+		t.Fatal(err)
+	}
+}
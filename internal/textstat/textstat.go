@@ -0,0 +1,213 @@
+package textstat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTopN est la taille par défaut des tableaux de fréquences.
+const DefaultTopN = 20
+
+// Counter cumule, ligne après ligne, les statistiques de mots d'un texte.
+// Les n-grammes enjambent les fins de ligne : une phrase coupée en deux
+// lignes reste une phrase.
+type Counter struct {
+	// nombre de mots et longueur cumulée au sens historique de fileops :
+	// mots séparés par des blancs, hors ceux commençant par un chiffre
+	words, sumLen int
+
+	tokens   int
+	freq     map[string]int
+	bigrams  map[string]int
+	trigrams map[string]int
+	lengths  map[int]int
+	prev     []string
+}
+
+// NewCounter crée un compteur vide.
+func NewCounter() *Counter {
+	return &Counter{
+		freq:     make(map[string]int),
+		bigrams:  make(map[string]int),
+		trigrams: make(map[string]int),
+		lengths:  make(map[int]int),
+	}
+}
+
+// Count compte les mots de lines (raccourci pour les petits textes).
+func Count(lines []string) *Counter {
+	c := NewCounter()
+	for _, l := range lines {
+		c.AddLine(l)
+	}
+	return c
+}
+
+// AddLine ajoute une ligne au compteur.
+func (c *Counter) AddLine(line string) {
+	for _, tok := range strings.Fields(line) {
+		if r, _ := utf8.DecodeRuneInString(tok); unicode.IsDigit(r) {
+			continue
+		}
+		c.words++
+		c.sumLen += len(tok)
+	}
+
+	for _, w := range Tokenize(line) {
+		c.tokens++
+		c.freq[w]++
+		c.lengths[utf8.RuneCountInString(w)]++
+		c.prev = append(c.prev, w)
+		if len(c.prev) > 3 {
+			c.prev = c.prev[1:]
+		}
+		if n := len(c.prev); n >= 2 {
+			c.bigrams[c.prev[n-2]+" "+c.prev[n-1]]++
+		}
+		if len(c.prev) == 3 {
+			c.trigrams[strings.Join(c.prev, " ")]++
+		}
+	}
+}
+
+// Words renvoie le nombre de mots et leur longueur moyenne (en octets).
+func (c *Counter) Words() (int, float64) {
+	if c.words == 0 {
+		return 0, 0
+	}
+	return c.words, float64(c.sumLen) / float64(c.words)
+}
+
+// Merge ajoute les comptes de o à c (vocabulaire d'un lot) ; les
+// n-grammes ne relient pas la fin d'un texte au début du suivant.
+func (c *Counter) Merge(o *Counter) {
+	c.words += o.words
+	c.sumLen += o.sumLen
+	c.tokens += o.tokens
+	for k, n := range o.freq {
+		c.freq[k] += n
+	}
+	for k, n := range o.bigrams {
+		c.bigrams[k] += n
+	}
+	for k, n := range o.trigrams {
+		c.trigrams[k] += n
+	}
+	for k, n := range o.lengths {
+		c.lengths[k] += n
+	}
+	c.prev = nil
+}
+
+// Tokenize découpe une ligne en mots normalisés (minuscules, sans
+// ponctuation ni apostrophes) ; les nombres sont ignorés.
+func Tokenize(line string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		out = append(out, strings.ToLower(w))
+	}
+	return out
+}
+
+// Freq est une entrée d'un tableau de fréquences.
+type Freq struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// LengthBin compte les mots d'une longueur donnée (en caractères).
+type LengthBin struct {
+	Length int `json:"length"`
+	Count  int `json:"count"`
+}
+
+// Vocab résume le vocabulaire d'un texte.
+type Vocab struct {
+	Tokens      int         `json:"tokens"`
+	Types       int         `json:"types"`
+	TTR         float64     `json:"ttr"`
+	Hapax       int         `json:"hapax"`
+	TopWords    []Freq      `json:"top_words"`
+	TopBigrams  []Freq      `json:"top_bigrams"`
+	TopTrigrams []Freq      `json:"top_trigrams"`
+	LengthHist  []LengthBin `json:"length_hist"`
+}
+
+// Vocab calcule le résumé avec des tableaux limités à topN entrées.
+func (c *Counter) Vocab(topN int) Vocab {
+	v := Vocab{
+		Tokens:      c.tokens,
+		Types:       len(c.freq),
+		TopWords:    top(c.freq, topN),
+		TopBigrams:  top(c.bigrams, topN),
+		TopTrigrams: top(c.trigrams, topN),
+	}
+	if c.tokens > 0 {
+		v.TTR = float64(v.Types) / float64(c.tokens)
+	}
+	for _, n := range c.freq {
+		if n == 1 {
+			v.Hapax++
+		}
+	}
+	for l, n := range c.lengths {
+		v.LengthHist = append(v.LengthHist, LengthBin{Length: l, Count: n})
+	}
+	sort.Slice(v.LengthHist, func(i, j int) bool { return v.LengthHist[i].Length < v.LengthHist[j].Length })
+	return v
+}
+
+// top trie m par fréquence décroissante puis ordre alphabétique.
+func top(m map[string]int, n int) []Freq {
+	out := make([]Freq, 0, len(m))
+	for k, v := range m {
+		out = append(out, Freq{Term: k, Count: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Term < out[j].Term
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// WriteText écrit le résumé sous forme de tableaux lisibles.
+func (v Vocab) WriteText(w io.Writer, title string) {
+	fmt.Fprintf(w, "=== Vocabulaire : %s ===\n", title)
+	fmt.Fprintf(w, "Mots : %d, vocabulaire : %d, TTR : %.3f, hapax : %d\n",
+		v.Tokens, v.Types, v.TTR, v.Hapax)
+	writeFreqs(w, "Mots les plus fréquents", v.TopWords)
+	writeFreqs(w, "Bigrammes", v.TopBigrams)
+	writeFreqs(w, "Trigrammes", v.TopTrigrams)
+
+	fmt.Fprintln(w, "\n-- Longueur des mots --")
+	maxCount := 0
+	for _, b := range v.LengthHist {
+		maxCount = max(maxCount, b.Count)
+	}
+	for _, b := range v.LengthHist {
+		bar := strings.Repeat("#", (b.Count*40+maxCount-1)/maxCount)
+		fmt.Fprintf(w, "%3d  %7d  %s\n", b.Length, b.Count, bar)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeFreqs(w io.Writer, title string, fs []Freq) {
+	fmt.Fprintf(w, "\n-- %s --\n", title)
+	for i, f := range fs {
+		fmt.Fprintf(w, "%3d. %-30s %d\n", i+1, f.Term, f.Count)
+	}
+}
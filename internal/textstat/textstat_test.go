@@ -0,0 +1,114 @@
+package textstat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"Le Chat, le chien.", []string{"le", "chat", "le", "chien"}},
+		{"l'été aujourd'hui", []string{"l", "été", "aujourd", "hui"}},
+		{"42 fois x2 3D", []string{"fois", "x2", "3d"}},
+		{"— « guillemets » …", []string{"guillemets"}},
+		{"ÉCOLE École", []string{"école", "école"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, veut %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCounterWords(t *testing.T) {
+	tests := []struct {
+		lines []string
+		words int
+		avg   float64
+	}{
+		{nil, 0, 0},
+		{[]string{"un deux", "trois"}, 3, 11.0 / 3},
+		// les mots commençant par un chiffre ne comptent pas
+		{[]string{"3 pommes 4x"}, 1, 6},
+		{[]string{"l'été"}, 1, 7}, // octets, pas caractères
+	}
+	for _, tt := range tests {
+		words, avg := Count(tt.lines).Words()
+		if words != tt.words || fmt.Sprintf("%.4f", avg) != fmt.Sprintf("%.4f", tt.avg) {
+			t.Errorf("Words(%q) = %d, %.4f ; veut %d, %.4f", tt.lines, words, avg, tt.words, tt.avg)
+		}
+	}
+}
+
+func freqs(fs []Freq) string {
+	var b []string
+	for _, f := range fs {
+		b = append(b, fmt.Sprintf("%s=%d", f.Term, f.Count))
+	}
+	return strings.Join(b, " ")
+}
+
+func TestVocab(t *testing.T) {
+	v := Count([]string{"le chat dort", "le chat mange", "Le chien"}).Vocab(2)
+	tests := []struct {
+		name, got, want string
+	}{
+		{"compte", fmt.Sprintf("%d %d %d %.3f", v.Tokens, v.Types, v.Hapax, v.TTR), "8 5 3 0.625"},
+		{"mots", freqs(v.TopWords), "le=3 chat=2"},
+		// les n-grammes enjambent les fins de ligne ; égalités par ordre alphabétique
+		{"bigrammes", freqs(v.TopBigrams), "le chat=2 chat dort=1"},
+		{"trigrammes", freqs(v.TopTrigrams), "chat dort le=1 chat mange le=1"},
+		{"longueurs", fmt.Sprint(v.LengthHist), "[{2 3} {4 3} {5 2}]"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s : %s, veut %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if e := NewCounter().Vocab(5); e.Tokens != 0 || e.TTR != 0 || len(e.TopWords) != 0 {
+		t.Errorf("vocabulaire vide : %+v", e)
+	}
+}
+
+func TestCounterMerge(t *testing.T) {
+	texts := [][]string{
+		{"le chat dort", "sur le tapis"},
+		{"le chien dort"},
+		{},
+		{"un chat, un chien : le chat"},
+	}
+	all := NewCounter()
+	for _, lines := range texts {
+		all.Merge(Count(lines))
+	}
+	// même résultat que des textes comptés à la suite, sans n-gramme
+	// entre la fin de l'un et le début du suivant
+	want := NewCounter()
+	for _, lines := range texts {
+		want.prev = nil
+		for _, l := range lines {
+			want.AddLine(l)
+		}
+	}
+	got, exp := all.Vocab(0), want.Vocab(0)
+	if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", exp) {
+		t.Errorf("Merge :\n %+v\nveut\n %+v", got, exp)
+	}
+	if got.Types != 7 || got.Hapax != 2 {
+		t.Errorf("types %d, hapax %d ; veut 7 et 2", got.Types, got.Hapax)
+	}
+	if slices.ContainsFunc(got.TopBigrams, func(f Freq) bool { return f.Term == "tapis le" }) {
+		t.Error("bigramme entre deux textes")
+	}
+	gw, ga := all.Words()
+	ww, wa := want.Words()
+	if gw != ww || ga != wa {
+		t.Errorf("Words() = %d, %v ; veut %d, %v", gw, ga, ww, wa)
+	}
+}
@@ -0,0 +1,264 @@
+package watch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fileops/internal/infra"
+	"fileops/internal/proc"
+)
+
+// Types de règles.
+const (
+	KindDisk      = "disk"
+	KindContainer = "container"
+	KindProcess   = "process"
+)
+
+// Rule est une condition surveillée, écrite « TYPE CIBLE CONDITION » :
+//
+//	disk * free<10          espace libre (%) de chaque montage
+//	disk /var inodes<5      inodes libres (%) du disque contenant /var
+//	container * cpu>80      CPU (%) de chaque conteneur
+//	container web mem>90    mémoire (% de la limite) du conteneur web
+//	process nginx missing   aucun processus nommé nginx
+//	process java rss>2G     mémoire résidente totale des processus java
+type Rule struct {
+	Text   string
+	Kind   string
+	Target string // * = toutes les cibles (disk, container)
+	Metric string // free, inodes, cpu, mem, rss, missing
+	Op     byte   // '<' ou '>'
+	Limit  float64
+}
+
+func (r Rule) String() string { return r.Text }
+
+// metrics liste les mesures de chaque type et le sens attendu.
+var metrics = map[string]map[string]byte{
+	KindDisk:      {"free": '<', "inodes": '<'},
+	KindContainer: {"cpu": '>', "mem": '>'},
+	KindProcess:   {"rss": '>', "missing": 0},
+}
+
+// ParseRule lit une règle (voir Rule).
+func ParseRule(s string) (Rule, error) {
+	f := strings.Fields(s)
+	if len(f) != 3 {
+		return Rule{}, fmt.Errorf("règle %q : « TYPE CIBLE CONDITION » attendu", s)
+	}
+	r := Rule{Text: strings.Join(f, " "), Kind: f[0], Target: f[1]}
+	ms, ok := metrics[r.Kind]
+	if !ok {
+		return r, fmt.Errorf("règle %q : type inconnu %s (disk, container, process)", s, r.Kind)
+	}
+	if r.Kind == KindProcess && r.Target == "*" {
+		return r, fmt.Errorf("règle %q : un processus se désigne par son nom", s)
+	}
+
+	cond := f[2]
+	i := strings.IndexAny(cond, "<>")
+	if i < 0 {
+		r.Metric = cond
+	} else {
+		r.Metric, r.Op = cond[:i], cond[i]
+	}
+	want, ok := ms[r.Metric]
+	if !ok {
+		return r, fmt.Errorf("règle %q : mesure inconnue %q pour %s", s, r.Metric, r.Kind)
+	}
+	if want == 0 {
+		if i >= 0 {
+			return r, fmt.Errorf("règle %q : %s ne prend pas de seuil", s, r.Metric)
+		}
+		return r, nil
+	}
+	if i < 0 {
+		return r, fmt.Errorf("règle %q : seuil attendu (%s%c N)", s, r.Metric, want)
+	}
+	if r.Op != want {
+		// position de l'opérateur dans s, en caractères à partir de 1
+		pos := utf8.RuneCountInString(s[:strings.LastIndex(s, cond)+i]) + 1
+		return r, fmt.Errorf("règle %q, position %d : « %c » inattendu, %s s'écrit %s%c N",
+			s, pos, r.Op, r.Metric, r.Metric, want)
+	}
+	val := cond[i+1:]
+	var err error
+	if r.Metric == "rss" {
+		var n uint64
+		n, err = parseSize(val)
+		r.Limit = float64(n)
+	} else {
+		r.Limit, err = strconv.ParseFloat(strings.TrimSuffix(val, "%"), 64)
+	}
+	if err != nil {
+		return r, fmt.Errorf("règle %q : seuil invalide %q", s, val)
+	}
+	return r, nil
+}
+
+// parseSize lit une taille : 512, 300K, 1.5M, 2G (puissances de 1024).
+func parseSize(s string) (uint64, error) {
+	mult := 1.0
+	if n := len(s); n > 0 {
+		switch strings.ToUpper(s[n-1:]) {
+		case "K":
+			mult = 1 << 10
+		case "M":
+			mult = 1 << 20
+		case "G":
+			mult = 1 << 30
+		case "T":
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("taille invalide %q", s)
+	}
+	return uint64(f * mult), nil
+}
+
+func (r Rule) breached(v float64) bool {
+	if r.Op == '<' {
+		return v < r.Limit
+	}
+	return v > r.Limit
+}
+
+// Finding est une règle franchie pour un sujet (montage, conteneur, nom).
+type Finding struct {
+	Rule    Rule
+	Subject string
+	Value   float64
+	Message string
+}
+
+// Key identifie une alerte entre deux vérifications.
+func (f Finding) Key() string { return f.Rule.Text + "|" + f.Subject }
+
+// Snapshot regroupe les mesures d'une vérification ; une source en échec
+// (Err) n'est ni évaluée ni considérée comme rétablie.
+type Snapshot struct {
+	Disks        []infra.DiskUsage
+	DiskErr      error
+	Containers   []infra.ContainerStats
+	ContainerErr error
+	Processes    []proc.Process
+	ProcessErr   error
+}
+
+// failed indique si la source de kind n'a pas pu être lue.
+func (s *Snapshot) failed(kind string) bool {
+	switch kind {
+	case KindDisk:
+		return s.DiskErr != nil
+	case KindContainer:
+		return s.ContainerErr != nil
+	case KindProcess:
+		return s.ProcessErr != nil
+	}
+	return false
+}
+
+// Evaluate renvoie les règles franchies dans s. Un montage n'est signalé
+// qu'une fois par mesure : une règle visant un chemin l'emporte sur *.
+func Evaluate(rules []Rule, s *Snapshot) []Finding {
+	var out []Finding
+	if !s.failed(KindDisk) {
+		seen := map[string]bool{}
+		for _, star := range []bool{false, true} {
+			for _, r := range rules {
+				if r.Kind != KindDisk || (r.Target == "*") != star {
+					continue
+				}
+				for _, f := range evalDisk(r, s.Disks) {
+					if k := f.Subject + "|" + r.Metric; !seen[k] {
+						seen[k] = true
+						out = append(out, f)
+					}
+				}
+			}
+		}
+	}
+	for _, r := range rules {
+		if s.failed(r.Kind) {
+			continue
+		}
+		switch r.Kind {
+		case KindContainer:
+			out = append(out, evalContainer(r, s.Containers)...)
+		case KindProcess:
+			if f, ok := evalProcess(r, s.Processes); ok {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+func evalDisk(r Rule, disks []infra.DiskUsage) []Finding {
+	var out []Finding
+	for _, d := range disks {
+		if r.Target != "*" && d.Path != r.Target {
+			continue
+		}
+		v, what := d.FreePct, "libre"
+		if r.Metric == "inodes" {
+			if d.Inodes == 0 {
+				continue
+			}
+			v, what = d.InodesFreePct, "d'inodes libres"
+		}
+		if r.breached(v) {
+			out = append(out, Finding{Rule: r, Subject: d.Mount, Value: v,
+				Message: fmt.Sprintf("disque %s : %.1f %% %s (seuil %c %g)", d.Mount, v, what, r.Op, r.Limit)})
+		}
+	}
+	return out
+}
+
+func evalContainer(r Rule, list []infra.ContainerStats) []Finding {
+	var out []Finding
+	for _, c := range list {
+		if r.Target != "*" && c.Name != r.Target && !strings.HasPrefix(c.ID, r.Target) {
+			continue
+		}
+		v, what := c.CPUPercent, "CPU"
+		if r.Metric == "mem" {
+			v, what = c.MemPercent, "mémoire"
+		}
+		if r.breached(v) {
+			out = append(out, Finding{Rule: r, Subject: c.Name, Value: v,
+				Message: fmt.Sprintf("conteneur %s : %s %.1f %% (seuil %c %g)", c.Name, what, v, r.Op, r.Limit)})
+		}
+	}
+	return out
+}
+
+func evalProcess(r Rule, procs []proc.Process) (Finding, bool) {
+	var n int
+	var rss uint64
+	for _, p := range procs {
+		if strings.EqualFold(p.Name, r.Target) {
+			n++
+			rss += p.RSS
+		}
+	}
+	f := Finding{Rule: r, Subject: r.Target}
+	switch r.Metric {
+	case "missing":
+		f.Message = fmt.Sprintf("processus %s absent", r.Target)
+		return f, n == 0
+	default:
+		f.Value = float64(rss)
+		f.Message = fmt.Sprintf("processus %s (%d) : RSS %.1f Mio (seuil %c %.1f Mio)",
+			r.Target, n, f.Value/(1<<20), r.Op, r.Limit/(1<<20))
+		return f, n > 0 && r.breached(f.Value)
+	}
+}
@@ -0,0 +1,132 @@
+package watch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"fileops/internal/infra"
+	"fileops/internal/proc"
+)
+
+func mustRules(t *testing.T, specs ...string) []Rule {
+	t.Helper()
+	var rules []Rule
+	for _, s := range specs {
+		r, err := ParseRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "disk  *  free<10", want: Rule{Text: "disk * free<10", Kind: KindDisk, Target: "*", Metric: "free", Op: '<', Limit: 10}},
+		{in: "container web mem>90%", want: Rule{Text: "container web mem>90%", Kind: KindContainer, Target: "web", Metric: "mem", Op: '>', Limit: 90}},
+		{in: "process java rss>1.5G", want: Rule{Text: "process java rss>1.5G", Kind: KindProcess, Target: "java", Metric: "rss", Op: '>', Limit: 1.5 * (1 << 30)}},
+		{in: "process nginx missing", want: Rule{Text: "process nginx missing", Kind: KindProcess, Target: "nginx", Metric: "missing"}},
+		{in: "disk /", wantErr: true},
+		{in: "net * rx>10", wantErr: true},
+		{in: "process * missing", wantErr: true},
+		{in: "disk * cpu>10", wantErr: true},
+		{in: "process nginx missing<1", wantErr: true},
+		{in: "disk * free", wantErr: true},
+		{in: "disk * free<beaucoup", wantErr: true},
+		// sens opposé à celui de la mesure
+		{in: "disk * free>10", wantErr: true},
+		{in: "container * cpu<5", wantErr: true},
+		{in: "process java rss<1G", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRule(%q) : err = %v", tt.in, err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, veut %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleOperator(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"disk * free>10", `règle "disk * free>10", position 12 : « > » inattendu, free s'écrit free< N`},
+		{"disk  /  inodes>5", `position 16 : « > » inattendu, inodes s'écrit inodes< N`},
+		{"container été mem<90", `position 18 : « < » inattendu, mem s'écrit mem> N`},
+		{"process java rss<1G", `position 17 : « < » inattendu, rss s'écrit rss> N`},
+	}
+	for _, tt := range tests {
+		_, err := ParseRule(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseRule(%q) : err = %v, veut %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	disks := []infra.DiskUsage{
+		{Path: "/", Mount: "/", FreePct: 5, Inodes: 100, InodesFreePct: 2},
+		{Path: "/var", Mount: "/", FreePct: 5, Inodes: 100, InodesFreePct: 2},
+		{Path: "/srv", Mount: "/srv", FreePct: 50},
+	}
+	snap := &Snapshot{
+		Disks: disks,
+		Containers: []infra.ContainerStats{
+			{ID: "abc123", Name: "web", CPUPercent: 95, MemPercent: 10},
+			{ID: "def456", Name: "db", CPUPercent: 10, MemPercent: 95},
+		},
+		Processes: []proc.Process{
+			{Name: "java", RSS: 1 << 30}, {Name: "Java", RSS: 1 << 30}, {Name: "nginx"},
+		},
+	}
+	tests := []struct {
+		name  string
+		rules []string
+		snap  *Snapshot
+		want  []string // Key() des constats
+	}{
+		{"montage signalé une fois", []string{"disk * free<10", "disk /var free<10"}, snap,
+			[]string{"disk /var free<10|/"}},
+		{"chemins du même montage", []string{"disk / free<10", "disk /var free<10"}, snap,
+			[]string{"disk / free<10|/"}},
+		{"une fois par mesure", []string{"disk * free<10", "disk * inodes<5"}, snap,
+			[]string{"disk * free<10|/", "disk * inodes<5|/"}},
+		{"pas d'inodes", []string{"disk /srv inodes<5"}, snap, nil},
+		{"conteneurs", []string{"container * cpu>80", "container def mem>90"}, snap,
+			[]string{"container * cpu>80|web", "container def mem>90|db"}},
+		{"processus", []string{"process java rss>1.5G", "process nginx missing", "process redis missing"}, snap,
+			[]string{"process java rss>1.5G|java", "process redis missing|redis"}},
+		{"source en échec", []string{"disk * free<10", "process redis missing"},
+			&Snapshot{Disks: disks, DiskErr: infra.ErrDaemonDown, ProcessErr: infra.ErrDaemonDown}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Evaluate(mustRules(t, tt.rules...), tt.snap) {
+				got = append(got, f.Key())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Evaluate = %q, veut %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateMessage(t *testing.T) {
+	snap := &Snapshot{Processes: []proc.Process{{Name: "java", RSS: 2 << 30}}}
+	got := Evaluate(mustRules(t, "process java rss>1.5G"), snap)
+	want := "processus java (1) : RSS 2048.0 Mio (seuil > 1536.0 Mio)"
+	if len(got) != 1 || got[0].Message != want {
+		t.Errorf("Evaluate = %+v, veut %q", got, want)
+	}
+}
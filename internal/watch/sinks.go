@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fileops/internal/secure"
+)
+
+// Sink reçoit les alertes ; String nomme la destination et sert à suivre
+// ce qu'elle a reçu.
+type Sink interface {
+	Send(ctx context.Context, a Alert) error
+	String() string
+}
+
+// ParseSink lit une destination : stdout, audit, file:CHEMIN ou
+// webhook:URL. Un chemin relatif est pris dans outDir.
+func ParseSink(spec, outDir string, jsonOut bool) (Sink, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch kind {
+	case "stdout":
+		return &WriterSink{W: os.Stdout, JSON: jsonOut}, nil
+	case "audit":
+		return AuditSink{OutDir: outDir}, nil
+	case "file":
+		if arg == "" {
+			return nil, fmt.Errorf("destination %q : chemin attendu (file:alertes.log)", spec)
+		}
+		if !filepath.IsAbs(arg) {
+			arg = filepath.Join(outDir, arg)
+		}
+		return &FileSink{Path: arg}, nil
+	case "webhook":
+		u, err := url.Parse(arg)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("destination %q : URL http(s) attendue", spec)
+		}
+		return &WebhookSink{URL: arg}, nil
+	}
+	return nil, fmt.Errorf("destination inconnue %q (stdout, audit, file:CHEMIN, webhook:URL)", spec)
+}
+
+// textLine formate une alerte sur une ligne.
+func textLine(a Alert) string {
+	return fmt.Sprintf("%s %-8s %s\n", a.Time.Format("2006-01-02 15:04:05"), strings.ToUpper(string(a.State)), a)
+}
+
+// WriterSink écrit une ligne par alerte (texte ou JSON).
+type WriterSink struct {
+	W    io.Writer
+	JSON bool
+	mu   sync.Mutex
+}
+
+func (s *WriterSink) String() string { return "stdout" }
+
+func (s *WriterSink) Send(_ context.Context, a Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.JSON {
+		return json.NewEncoder(s.W).Encode(a)
+	}
+	_, err := io.WriteString(s.W, textLine(a))
+	return err
+}
+
+// FileSink ajoute chaque alerte à un fichier.
+type FileSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileSink) String() string { return "file:" + s.Path }
+
+func (s *FileSink) Send(_ context.Context, a Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, textLine(a)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AuditSink trace les alertes dans le journal d'audit (secure.Log).
+type AuditSink struct {
+	OutDir string
+}
+
+func (s AuditSink) String() string { return "audit" }
+
+func (s AuditSink) Send(_ context.Context, a Alert) error {
+	return secure.Log(s.OutDir, "WATCH "+strings.ToUpper(string(a.State)), a.Rule+" : "+a.String())
+}
+
+// WebhookSink envoie chaque alerte en JSON (POST) ; toute réponse hors
+// 2xx est une erreur.
+type WebhookSink struct {
+	URL  string
+	HTTP *http.Client // nil : délai de 10 s
+}
+
+func (s *WebhookSink) String() string { return "webhook:" + s.URL }
+
+func (s *WebhookSink) Send(ctx context.Context, a Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := s.HTTP
+	if c == nil {
+		c = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
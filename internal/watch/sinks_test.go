@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSink(t *testing.T) {
+	al := Alert{
+		Time: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), State: StateFiring,
+		Rule: "disk * free<10", Subject: "/", Value: 4.2, Message: "disque / : 4.2 % libre (seuil < 10)",
+		Since: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{"200", http.StatusOK, ""},
+		{"204", http.StatusNoContent, ""},
+		{"304", http.StatusNotModified, "HTTP 304"},
+		{"404", http.StatusNotFound, "HTTP 404"},
+		{"500", http.StatusInternalServerError, "HTTP 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Alert
+			var ctype string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("méthode %s", r.Method)
+				}
+				ctype = r.Header.Get("Content-Type")
+				b, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(b, &got); err != nil {
+					t.Errorf("corps illisible %q : %v", b, err)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, "ignoré")
+			}))
+			defer srv.Close()
+
+			s := &WebhookSink{URL: srv.URL, HTTP: srv.Client()}
+			err := s.Send(context.Background(), al)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, veut %q", err, tt.wantErr)
+			}
+			if ctype != "application/json" {
+				t.Errorf("Content-Type %q", ctype)
+			}
+			if !got.Time.Equal(al.Time) || got.Message != al.Message || got.Subject != al.Subject || got.State != al.State {
+				t.Errorf("reçu %+v, veut %+v", got, al)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	s := &WebhookSink{URL: url}
+	if err := s.Send(context.Background(), Alert{}); err == nil {
+		t.Error("pas d'erreur pour un serveur arrêté")
+	}
+}
+
+func TestParseSink(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"stdout", "stdout", false},
+		{"audit", "audit", false},
+		{"file:alertes.log", "file:out/alertes.log", false},
+		{"file:/var/log/a.log", "file:/var/log/a.log", false},
+		{"file:", "", true},
+		{"webhook:https://hooks.example.com/x", "webhook:https://hooks.example.com/x", false},
+		{"webhook:ftp://example.com", "", true},
+		{"webhook:", "", true},
+		{"mail:root", "", true},
+	}
+	for _, tt := range tests {
+		s, err := ParseSink(tt.spec, "out", false)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSink(%q) : err = %v", tt.spec, err)
+			continue
+		}
+		if err == nil && s.String() != tt.want {
+			t.Errorf("ParseSink(%q) = %s, veut %s", tt.spec, s, tt.want)
+		}
+	}
+}
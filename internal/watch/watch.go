@@ -0,0 +1,286 @@
+// Package watch évalue périodiquement des règles sur les disques, les
+// conteneurs et les processus, et envoie les alertes à des destinations.
+package watch
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"fileops/internal/infra"
+	"fileops/internal/proc"
+)
+
+// State est l'état d'une alerte envoyée.
+type State string
+
+const (
+	StateFiring   State = "alerte"
+	StateResolved State = "rétabli"
+)
+
+// Alert est un changement d'état d'une règle pour un sujet, ou le rappel
+// d'une alerte toujours active (Repeat).
+type Alert struct {
+	Time    time.Time `json:"time"`
+	State   State     `json:"state"`
+	Rule    string    `json:"rule"`
+	Subject string    `json:"subject"`
+	Value   float64   `json:"value"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"` // début de l'alerte
+	Repeat  bool      `json:"repeat,omitempty"`
+}
+
+func (a Alert) String() string {
+	s := a.Message
+	if a.State == StateResolved {
+		s = "rétabli après " + a.Time.Sub(a.Since).Round(time.Second).String() + " : " + s
+	}
+	return s
+}
+
+// active est une alerte en cours. fired note les destinations (par nom)
+// qui ont reçu l'alerte, cleared celles qui ont reçu le rétablissement ;
+// resolved marque un rétablissement constaté mais pas encore reçu par
+// toutes les destinations de fired.
+type active struct {
+	since, sent time.Time
+	f           Finding
+	fired       map[string]bool
+	cleared     map[string]bool
+	resolved    bool
+}
+
+// Watcher évalue Rules toutes les Interval et n'envoie aux Sinks que les
+// changements : première alerte, puis rétablissement. Repeat > 0 renvoie
+// une alerte toujours active après ce délai. Un envoi est retenté à la
+// vérification suivante vers les seules destinations qui l'ont refusé.
+type Watcher struct {
+	Rules    []Rule
+	Sinks    []Sink
+	Interval time.Duration
+	Repeat   time.Duration
+	Docker   *infra.Docker // nil : règles container en erreur
+	// Errors reçoit les erreurs de lecture et d'envoi (facultatif) ; une
+	// même erreur de source n'est signalée qu'une fois.
+	Errors func(error)
+
+	alerts  map[string]*active
+	lastErr map[string]string
+}
+
+// Run vérifie les règles jusqu'à l'annulation de ctx.
+func (w *Watcher) Run(ctx context.Context) error {
+	t := time.NewTicker(w.Interval)
+	defer t.Stop()
+	for {
+		w.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Check fait une vérification, envoie les changements (et les envois
+// restés sans destinataire) et les renvoie.
+func (w *Watcher) Check(ctx context.Context) []Alert {
+	if w.alerts == nil {
+		w.alerts, w.lastErr = map[string]*active{}, map[string]string{}
+	}
+	snap := w.collect()
+	now := time.Now()
+
+	type pending struct {
+		key string
+		al  Alert
+		to  []Sink
+	}
+	var out []pending
+	firing := map[string]bool{}
+	for _, f := range Evaluate(w.Rules, snap) {
+		k := f.Key()
+		firing[k] = true
+		a, ok := w.alerts[k]
+		if !ok {
+			a = &active{since: now, f: f, fired: map[string]bool{}}
+			w.alerts[k] = a
+			out = append(out, pending{k, alertOf(f, StateFiring, now, now), w.Sinks})
+			continue
+		}
+		a.f = f
+		if a.resolved {
+			// de nouveau franchie avant que le rétablissement soit reçu
+			// partout : l'alerte est renvoyée à ceux qui l'ont reçu
+			for name := range a.cleared {
+				delete(a.fired, name)
+			}
+			a.cleared, a.resolved = nil, false
+		}
+		switch missing := w.sinksWhere(a.fired, false); {
+		case len(missing) > 0:
+			out = append(out, pending{k, alertOf(f, StateFiring, now, a.since), missing})
+		case w.Repeat > 0 && now.Sub(a.sent) >= w.Repeat:
+			al := alertOf(f, StateFiring, now, a.since)
+			al.Repeat = true
+			out = append(out, pending{k, al, w.Sinks})
+		}
+	}
+	for k, a := range w.alerts {
+		// une source illisible ne rétablit rien
+		if firing[k] || snap.failed(a.f.Rule.Kind) {
+			continue
+		}
+		if len(a.fired) == 0 && len(w.Sinks) > 0 {
+			// jamais reçue : rien à rétablir
+			delete(w.alerts, k)
+			continue
+		}
+		if a.cleared == nil {
+			a.cleared = map[string]bool{}
+		}
+		a.resolved = true
+		var to []Sink
+		for _, s := range w.sinksWhere(a.fired, true) {
+			if !a.cleared[s.String()] {
+				to = append(to, s)
+			}
+		}
+		out = append(out, pending{k, alertOf(a.f, StateResolved, now, a.since), to})
+	}
+	slices.SortStableFunc(out, func(a, b pending) int {
+		return cmp.Or(cmp.Compare(a.al.Rule, b.al.Rule), cmp.Compare(a.al.Subject, b.al.Subject))
+	})
+
+	alerts := make([]Alert, len(out))
+	for i, p := range out {
+		alerts[i] = p.al
+		got := w.send(ctx, p.al, p.to)
+		a := w.alerts[p.key]
+		if p.al.State == StateResolved {
+			for _, name := range got {
+				a.cleared[name] = true
+			}
+			if len(got) == len(p.to) {
+				delete(w.alerts, p.key)
+			}
+			continue
+		}
+		for _, name := range got {
+			a.fired[name] = true
+		}
+		if len(got) > 0 || len(p.to) == 0 {
+			a.sent = now
+		}
+	}
+	return alerts
+}
+
+// sinksWhere renvoie les destinations dont la présence dans names vaut in.
+func (w *Watcher) sinksWhere(names map[string]bool, in bool) []Sink {
+	var res []Sink
+	for _, s := range w.Sinks {
+		if names[s.String()] == in {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// send envoie al aux destinations to et renvoie le nom de celles qui
+// l'ont reçue.
+func (w *Watcher) send(ctx context.Context, al Alert, to []Sink) []string {
+	var got []string
+	for _, s := range to {
+		if err := s.Send(ctx, al); err != nil {
+			w.report(fmt.Errorf("envoi vers %s : %w", s, err))
+			continue
+		}
+		got = append(got, s.String())
+	}
+	return got
+}
+
+func alertOf(f Finding, st State, now, since time.Time) Alert {
+	return Alert{Time: now, State: st, Rule: f.Rule.Text, Subject: f.Subject,
+		Value: f.Value, Message: f.Message, Since: since}
+}
+
+// collect lit seulement les sources utilisées par les règles.
+func (w *Watcher) collect() *Snapshot {
+	snap := &Snapshot{}
+	need := map[string]bool{}
+	for _, r := range w.Rules {
+		need[r.Kind] = true
+	}
+	if need[KindDisk] {
+		snap.Disks, snap.DiskErr = w.disks()
+		w.sourceErr(KindDisk, snap.DiskErr)
+	}
+	if need[KindContainer] {
+		if w.Docker == nil {
+			snap.ContainerErr = fmt.Errorf("Docker non configuré")
+		} else {
+			snap.Containers, snap.ContainerErr = w.Docker.StatsAll(infra.SortCPU)
+		}
+		w.sourceErr(KindContainer, snap.ContainerErr)
+	}
+	if need[KindProcess] {
+		snap.Processes, snap.ProcessErr = proc.List()
+		w.sourceErr(KindProcess, snap.ProcessErr)
+	}
+	return snap
+}
+
+// disks lit tous les montages si une règle vise *, et chaque chemin visé.
+func (w *Watcher) disks() ([]infra.DiskUsage, error) {
+	var res []infra.DiskUsage
+	all := false
+	for _, r := range w.Rules {
+		if r.Kind != KindDisk {
+			continue
+		}
+		if r.Target == "*" {
+			all = true
+			continue
+		}
+		u, err := infra.Usage(r.Target)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	if all {
+		report, err := infra.DiskReport(infra.Thresholds{})
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, report...)
+	}
+	return res, nil
+}
+
+// sourceErr signale une erreur de source à son apparition et à son
+// changement, pas à chaque vérification.
+func (w *Watcher) sourceErr(kind string, err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if msg != w.lastErr[kind] {
+		w.lastErr[kind] = msg
+		if err != nil {
+			w.report(fmt.Errorf("%s : %w", kind, err))
+		}
+	}
+}
+
+func (w *Watcher) report(err error) {
+	if w.Errors != nil {
+		w.Errors(err)
+	}
+}
@@ -0,0 +1,210 @@
+package watch
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakeSink enregistre les alertes reçues ; fail refuse les envois.
+type fakeSink struct {
+	name string // "" = fake
+	fail bool
+	got  []Alert
+}
+
+func (s *fakeSink) String() string { return cmp.Or(s.name, "fake") }
+
+func (s *fakeSink) Send(_ context.Context, a Alert) error {
+	if s.fail {
+		return errors.New("indisponible")
+	}
+	s.got = append(s.got, a)
+	return nil
+}
+
+// step est une vérification : règle franchie ou non, destination en
+// panne ou non, et alertes attendues (état et rappel).
+type step struct {
+	firing bool
+	fail   bool
+	want   []string // « alerte », « rétabli » ou « rappel »
+	recv   int      // alertes reçues en tout par la destination
+}
+
+func TestWatcherCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		repeat bool
+		steps  []step
+	}{
+		{"une seule alerte", false, []step{
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: true, recv: 1},
+			{firing: true, recv: 1},
+		}},
+		{"rétablissement", false, []step{
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: false, want: []string{"rétabli"}, recv: 2},
+			{firing: false, recv: 2},
+			{firing: true, want: []string{"alerte"}, recv: 3},
+		}},
+		{"envoi retenté", false, []step{
+			{firing: true, fail: true, want: []string{"alerte"}},
+			{firing: true, fail: true, want: []string{"alerte"}},
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: true, recv: 1},
+		}},
+		{"jamais reçue, rien à rétablir", false, []step{
+			{firing: true, fail: true, want: []string{"alerte"}},
+			{firing: false},
+			{firing: false},
+		}},
+		{"rétablissement retenté", false, []step{
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: false, fail: true, want: []string{"rétabli"}, recv: 1},
+			{firing: false, want: []string{"rétabli"}, recv: 2},
+			{firing: false, recv: 2},
+		}},
+		{"franchie avant la réception du rétablissement", false, []step{
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: false, fail: true, want: []string{"rétabli"}, recv: 1},
+			{firing: true, recv: 1},
+			{firing: true, recv: 1},
+		}},
+		{"rappel", true, []step{
+			{firing: true, want: []string{"alerte"}, recv: 1},
+			{firing: true, want: []string{"rappel"}, recv: 2},
+			{firing: true, fail: true, want: []string{"rappel"}, recv: 2},
+			{firing: false, want: []string{"rétabli"}, recv: 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// l'espace libre du disque de TempDir est toujours < 101 %
+			// et jamais < -1 % : le seuil bascule l'état de la règle
+			rules := mustRules(t, "disk "+t.TempDir()+" free<101")
+			sink := &fakeSink{}
+			w := &Watcher{Rules: rules, Sinks: []Sink{sink}}
+			if tt.repeat {
+				w.Repeat = 1
+			}
+			for i, st := range tt.steps {
+				w.Rules[0].Limit = 101
+				if !st.firing {
+					w.Rules[0].Limit = -1
+				}
+				sink.fail = st.fail
+
+				var got []string
+				for _, a := range w.Check(context.Background()) {
+					if a.Repeat {
+						got = append(got, "rappel")
+					} else {
+						got = append(got, string(a.State))
+					}
+				}
+				if fmt.Sprint(got) != fmt.Sprint(st.want) {
+					t.Errorf("étape %d : alertes %q, veut %q", i, got, st.want)
+				}
+				if len(sink.got) != st.recv {
+					t.Errorf("étape %d : %d alertes reçues, veut %d", i, len(sink.got), st.recv)
+				}
+			}
+		})
+	}
+}
+
+func TestWatcherCheckSince(t *testing.T) {
+	sink := &fakeSink{fail: true}
+	w := &Watcher{Rules: mustRules(t, "disk "+t.TempDir()+" free<101"), Sinks: []Sink{sink}}
+	first := w.Check(context.Background())
+	sink.fail = false
+	retry := w.Check(context.Background())
+	w.Rules[0].Limit = -1
+	resolved := w.Check(context.Background())
+	if len(first) != 1 || len(retry) != 1 || len(resolved) != 1 {
+		t.Fatalf("alertes %v, %v, %v", first, retry, resolved)
+	}
+	// l'alerte retentée et le rétablissement gardent le début de l'alerte
+	if !retry[0].Since.Equal(first[0].Since) || !resolved[0].Since.Equal(first[0].Since) {
+		t.Errorf("Since %v puis %v et %v", first[0].Since, retry[0].Since, resolved[0].Since)
+	}
+	if retry[0].Time.Before(first[0].Time) {
+		t.Errorf("Time de l'alerte retentée %v, premier essai %v", retry[0].Time, first[0].Time)
+	}
+}
+
+// sinkStep : règle franchie ou non, panne de la destination b, et
+// alertes reçues en tout par a et b (« alerte », « rétabli »).
+type sinkStep struct {
+	firing, failB bool
+	a, b          []string
+	errs          int
+}
+
+func TestWatcherSinkErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sinkStep
+	}{
+		{"envoi retenté vers la destination en panne seule", []sinkStep{
+			{firing: true, failB: true, a: []string{"alerte"}, errs: 1},
+			{firing: true, failB: true, a: []string{"alerte"}, errs: 2},
+			{firing: true, a: []string{"alerte"}, b: []string{"alerte"}, errs: 2},
+			{firing: true, a: []string{"alerte"}, b: []string{"alerte"}, errs: 2},
+			{firing: false, a: []string{"alerte", "rétabli"}, b: []string{"alerte", "rétabli"}, errs: 2},
+		}},
+		{"rétabli pour ceux qui ont reçu l'alerte", []sinkStep{
+			{firing: true, failB: true, a: []string{"alerte"}, errs: 1},
+			{firing: false, a: []string{"alerte", "rétabli"}, errs: 1},
+			{firing: false, a: []string{"alerte", "rétabli"}, errs: 1},
+		}},
+		{"rétablissement retenté", []sinkStep{
+			{firing: true, a: []string{"alerte"}, b: []string{"alerte"}},
+			{firing: false, failB: true, a: []string{"alerte", "rétabli"}, b: []string{"alerte"}, errs: 1},
+			{firing: false, a: []string{"alerte", "rétabli"}, b: []string{"alerte", "rétabli"}, errs: 1},
+			{firing: false, a: []string{"alerte", "rétabli"}, b: []string{"alerte", "rétabli"}, errs: 1},
+		}},
+		{"franchie après un rétablissement reçu par une seule", []sinkStep{
+			{firing: true, a: []string{"alerte"}, b: []string{"alerte"}},
+			{firing: false, failB: true, a: []string{"alerte", "rétabli"}, b: []string{"alerte"}, errs: 1},
+			{firing: true, a: []string{"alerte", "rétabli", "alerte"}, b: []string{"alerte"}, errs: 1},
+			{firing: true, a: []string{"alerte", "rétabli", "alerte"}, b: []string{"alerte"}, errs: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+			a, b := &fakeSink{name: "a"}, &fakeSink{name: "b"}
+			w := &Watcher{
+				Rules:  mustRules(t, "disk "+t.TempDir()+" free<101"),
+				Sinks:  []Sink{a, b},
+				Errors: func(err error) { errs = append(errs, err) },
+			}
+			states := func(s *fakeSink) []string {
+				var res []string
+				for _, al := range s.got {
+					res = append(res, string(al.State))
+				}
+				return res
+			}
+			for i, st := range tt.steps {
+				w.Rules[0].Limit = 101
+				if !st.firing {
+					w.Rules[0].Limit = -1
+				}
+				b.fail = st.failB
+				w.Check(context.Background())
+				if fmt.Sprint(states(a)) != fmt.Sprint(st.a) || fmt.Sprint(states(b)) != fmt.Sprint(st.b) {
+					t.Errorf("étape %d : a %q, b %q ; veut %q, %q", i, states(a), states(b), st.a, st.b)
+				}
+				if len(errs) != st.errs {
+					t.Errorf("étape %d : %d erreurs, veut %d", i, len(errs), st.errs)
+				}
+			}
+		})
+	}
+}
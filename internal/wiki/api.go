@@ -0,0 +1,195 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIPath est le chemin de api.php sur les wikis Wikimedia.
+const DefaultAPIPath = "/w/api.php"
+
+// ErrNotFound : l'article n'existe pas (inutile d'essayer le HTML).
+var ErrNotFound = errors.New("article introuvable")
+
+// réponse de action=query (formatversion=2)
+type apiResponse struct {
+	Error *struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+	Query struct {
+		Pages []struct {
+			PageID    int    `json:"pageid"`
+			Title     string `json:"title"`
+			Missing   bool   `json:"missing"`
+			Invalid   bool   `json:"invalid"`
+			Extract   string `json:"extract"`
+			Revisions []struct {
+				RevID     int       `json:"revid"`
+				Timestamp time.Time `json:"timestamp"`
+			} `json:"revisions"`
+			Categories []struct {
+				Title string `json:"title"`
+			} `json:"categories"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// APIURL renvoie l'adresse de la requête action=query pour title.
+func (c *Client) APIURL(lang, title string) string {
+	base := strings.ReplaceAll(c.BaseURL, "{lang}", lang)
+	q := url.Values{
+		"action":          {"query"},
+		"format":          {"json"},
+		"formatversion":   {"2"},
+		"redirects":       {"1"},
+		"prop":            {"extracts|revisions|categories|info"},
+		"explaintext":     {"1"},
+		"exsectionformat": {"wiki"},
+		"rvprop":          {"ids|timestamp"},
+		"cllimit":         {"max"},
+		"clshow":          {"!hidden"},
+		"titles":          {title},
+	}
+	return base + c.APIPath + "?" + q.Encode()
+}
+
+// fetchAPI lit l'article via l'API MediaWiki : texte brut, sections,
+// révision et catégories, titre canonique après redirection.
+func (c *Client) fetchAPI(ctx context.Context, lang, title string) (*Article, error) {
+	u := c.APIURL(lang, title)
+	body, info, err := c.fetchBody(ctx, lang, title, "api", u)
+	if err != nil {
+		return nil, err
+	}
+
+	var r apiResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("réponse API illisible : %w", err)
+	}
+	if r.Error != nil {
+		return nil, fmt.Errorf("API %s : %s", r.Error.Code, r.Error.Info)
+	}
+	if len(r.Query.Pages) == 0 {
+		return nil, errors.New("réponse API sans page")
+	}
+	p := r.Query.Pages[0]
+	if p.Missing || p.Invalid {
+		return nil, fmt.Errorf("%s:%s : %w", lang, title, ErrNotFound)
+	}
+	if strings.TrimSpace(p.Extract) == "" {
+		// wiki sans l'extension TextExtracts : le HTML prendra le relais
+		return nil, errors.New("extrait vide")
+	}
+
+	paras, sections := splitExtract(p.Extract)
+	a := newArticle(p.Title, lang, c.PageURL(lang, p.Title), paras)
+	a.Source, a.Cache, a.Fetched = "api", info.Cache, info.Fetched
+	a.PageID = p.PageID
+	a.Sections = sections
+	if p.Title != title {
+		a.Requested = title
+	}
+	if len(p.Revisions) > 0 {
+		a.RevID = p.Revisions[0].RevID
+		a.Timestamp = p.Revisions[0].Timestamp
+	}
+	for _, cat := range p.Categories {
+		// « Catégorie:Langage de programmation » → « Langage de programmation »
+		_, name, ok := strings.Cut(cat.Title, ":")
+		if !ok {
+			name = cat.Title
+		}
+		a.Categories = append(a.Categories, name)
+	}
+	return a, nil
+}
+
+// splitExtract sépare un extrait en paragraphes et sections (lignes
+// « == Titre == », le nombre de = donnant le niveau).
+func splitExtract(extract string) (paras []string, sections []Section) {
+	for _, l := range strings.Split(extract, "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == "":
+		case strings.HasPrefix(l, "==") && strings.HasSuffix(l, "=="):
+			level := len(l) - len(strings.TrimLeft(l, "="))
+			if t := strings.TrimSpace(strings.Trim(l, "=")); t != "" {
+				sections = append(sections, Section{Title: t, Level: level, Start: len(paras)})
+			}
+		default:
+			paras = append(paras, l)
+		}
+	}
+	return paras, sections
+}
+
+// Link est un lien interne d'un article ; NS est l'espace de noms
+// MediaWiki (0 = articles ; pour les liens lus dans le HTML, déduit du
+// préfixe du titre, voir namespaceOf).
+type Link struct {
+	Title string `json:"title"`
+	NS    int    `json:"ns"`
+}
+
+// réponse de prop=links, par pages de 500 liens
+type linksResponse struct {
+	Continue map[string]string `json:"continue"`
+	Query    struct {
+		Pages []struct {
+			Links []Link `json:"links"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Links renvoie les liens internes de a : ceux de la page HTML si elle a
+// servi de source, sinon ceux de l'API (toutes les pages de résultats).
+func (c *Client) Links(ctx context.Context, a *Article) ([]Link, error) {
+	if a.links != nil || c.APIPath == "" {
+		ns := c.namespaces(ctx, a.Lang)
+		for i := range a.links {
+			a.links[i].NS = namespaceOf(a.links[i].Title, ns)
+		}
+		return a.links, nil
+	}
+	base := strings.ReplaceAll(c.BaseURL, "{lang}", a.Lang)
+	var links []Link
+	cont := map[string]string{}
+	for page := 0; ; page++ {
+		q := url.Values{
+			"action":        {"query"},
+			"format":        {"json"},
+			"formatversion": {"2"},
+			"prop":          {"links"},
+			"pllimit":       {"max"},
+			"titles":        {a.Title},
+		}
+		for k, v := range cont {
+			q.Set(k, v)
+		}
+		kind := "links"
+		if page > 0 {
+			kind = fmt.Sprintf("links-%d", page)
+		}
+		body, _, err := c.fetchBody(ctx, a.Lang, a.Title, kind, base+c.APIPath+"?"+q.Encode())
+		if err != nil {
+			return links, err
+		}
+		var r linksResponse
+		if err := json.Unmarshal(body, &r); err != nil {
+			return links, fmt.Errorf("réponse API illisible : %w", err)
+		}
+		for _, p := range r.Query.Pages {
+			links = append(links, p.Links...)
+		}
+		if r.Continue["plcontinue"] == "" {
+			return links, nil
+		}
+		cont = r.Continue
+	}
+}
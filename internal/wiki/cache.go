@@ -0,0 +1,154 @@
+package wiki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// État du cache pour une réponse.
+const (
+	CacheHit         = "hit"         // servie du cache, encore fraîche
+	CacheRevalidated = "revalidated" // 304 : copie locale confirmée
+	CacheStale       = "stale"       // réseau en échec, copie périmée servie
+	CacheMiss        = "miss"        // téléchargée
+)
+
+// ErrOffline : mode hors ligne et réponse absente du cache.
+var ErrOffline = errors.New("absent du cache (mode hors ligne)")
+
+// Cache conserve les réponses HTTP sous Dir, par langue et titre, avec
+// leur ETag et Last-Modified pour les requêtes conditionnelles.
+type Cache struct {
+	Dir string
+	// MaxAge : durée pendant laquelle une copie est servie sans
+	// revalidation (0 = toujours revalider).
+	MaxAge time.Duration
+	// Offline : ne jamais contacter le serveur.
+	Offline bool
+
+	hits, revalidated, stale, misses atomic.Int64
+}
+
+// CacheStats compte les réponses servies depuis la création du cache.
+type CacheStats struct {
+	Hits        int64 `json:"hits"`
+	Revalidated int64 `json:"revalidated"`
+	Stale       int64 `json:"stale"`
+	Misses      int64 `json:"misses"`
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%d hit, %d revalidé, %d périmé, %d miss",
+		s.Hits, s.Revalidated, s.Stale, s.Misses)
+}
+
+// NewCache crée un cache dans dir.
+func NewCache(dir string, maxAge time.Duration, offline bool) *Cache {
+	return &Cache{Dir: dir, MaxAge: maxAge, Offline: offline}
+}
+
+// CacheDir renvoie le dossier du cache wiki sous outDir.
+func CacheDir(outDir string) string {
+	return filepath.Join(outDir, "cache", "wiki")
+}
+
+// Stats renvoie les compteurs.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:        c.hits.Load(),
+		Revalidated: c.revalidated.Load(),
+		Stale:       c.stale.Load(),
+		Misses:      c.misses.Load(),
+	}
+}
+
+func (c *Cache) count(state string) {
+	switch state {
+	case CacheHit:
+		c.hits.Add(1)
+	case CacheRevalidated:
+		c.revalidated.Add(1)
+	case CacheStale:
+		c.stale.Add(1)
+	case CacheMiss:
+		c.misses.Add(1)
+	}
+}
+
+// cacheMeta accompagne chaque corps enregistré.
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	Lang         string    `json:"lang"`
+	Title        string    `json:"title"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+func (m cacheMeta) fresh(maxAge time.Duration) bool {
+	return maxAge > 0 && time.Since(m.Fetched) < maxAge
+}
+
+// paths renvoie les fichiers d'une entrée ; kind distingue API et HTML.
+func (c *Cache) paths(lang, title, kind string) (meta, body string) {
+	sum := sha256.Sum256([]byte(title))
+	base := filepath.Join(c.Dir, lang, hex.EncodeToString(sum[:12])+"."+kind)
+	return base + ".json", base + ".body"
+}
+
+func (c *Cache) load(lang, title, kind string) (cacheMeta, []byte, bool) {
+	var m cacheMeta
+	mp, bp := c.paths(lang, title, kind)
+	b, err := os.ReadFile(mp)
+	if err != nil || json.Unmarshal(b, &m) != nil {
+		return m, nil, false
+	}
+	body, err := os.ReadFile(bp)
+	if err != nil {
+		return m, nil, false
+	}
+	return m, body, true
+}
+
+// store écrit l'entrée ; le corps d'abord, puis les métadonnées, chacun
+// via un fichier temporaire renommé (téléchargements concurrents).
+func (c *Cache) store(lang, title, kind string, m cacheMeta, body []byte) error {
+	mp, bp := c.paths(lang, title, kind)
+	if err := os.MkdirAll(filepath.Dir(mp), 0o755); err != nil {
+		return err
+	}
+	if body != nil {
+		if err := writeAtomic(bp, body); err != nil {
+			return err
+		}
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(mp, b)
+}
+
+func writeAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
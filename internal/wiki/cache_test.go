@@ -0,0 +1,84 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheConditional(t *testing.T) {
+	ok := reply{status: 200, etag: `"v1"`, body: apiBody}
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		offline bool
+		replies []reply // après le premier téléchargement
+		cache   string
+		inm     []string // If-None-Match des requêtes suivantes
+		wantErr bool
+	}{
+		{"304 revalidé", 0, false, []reply{{status: 304}}, CacheRevalidated, []string{`"v1"`}, false},
+		{"200 remplacé", 0, false, []reply{{status: 200, etag: `"v2"`, body: apiBody}}, CacheMiss, []string{`"v1"`}, false},
+		{"encore frais", time.Hour, false, nil, CacheHit, nil, false},
+		{"hors ligne", 0, true, nil, CacheHit, nil, false},
+		{"serveur en panne", 0, false, []reply{{status: 503}}, CacheStale, []string{`"v1"`}, false},
+		{"404", 0, false, []reply{{status: 404}}, "", []string{`"v1"`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAPIServer(t, append([]reply{ok}, tt.replies...)...)
+			c := testClient(s)
+			c.Retries = 0
+			c.Cache = NewCache(t.TempDir(), 0, false)
+
+			ctx := context.Background()
+			a, err := c.fetchAPI(ctx, "fr", "Go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Cache != CacheMiss {
+				t.Fatalf("premier téléchargement : %q", a.Cache)
+			}
+
+			c.Cache.MaxAge, c.Cache.Offline = tt.maxAge, tt.offline
+			a, err = c.fetchAPI(ctx, "fr", "Go")
+			if got := s.requests()[1:]; !slices.Equal(got, tt.inm) {
+				t.Errorf("If-None-Match %q, veut %q", got, tt.inm)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("pas d'erreur")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Cache != tt.cache || a.Title != "Go (langage)" {
+				t.Errorf("cache %q, titre %q ; veut %q", a.Cache, a.Title, tt.cache)
+			}
+		})
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	s := newAPIServer(t, reply{status: 200, etag: `"v1"`, body: apiBody}, reply{status: 304})
+	c := testClient(s)
+	c.Cache = NewCache(t.TempDir(), 0, false)
+	ctx := context.Background()
+	for range 3 {
+		if _, err := c.fetchAPI(ctx, "fr", "Go"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Cache.Offline = true
+	if _, err := c.fetchAPI(ctx, "fr", "Rust"); !errors.Is(err, ErrOffline) {
+		t.Errorf("titre absent hors ligne : %v", err)
+	}
+	want := CacheStats{Misses: 1, Revalidated: 2}
+	if got := c.Cache.Stats(); got != want {
+		t.Errorf("Stats() = %+v, veut %+v", got, want)
+	}
+}
@@ -0,0 +1,306 @@
+package wiki
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// CrawlOptions règle Crawl. Les fichiers vides prennent leur nom par
+// défaut dans OutDir (crawl_state.json, crawl_edges.tsv, crawl.dot).
+type CrawlOptions struct {
+	Seeds      []string // titres de départ, « lang:Titre » accepté
+	MaxDepth   int      // 0 = graines seulement
+	MaxPages   int      // articles téléchargés au plus
+	Namespaces []int    // espaces de noms suivis (vide = 0, articles)
+	OutDir     string
+	Format     SaveFormat // format des articles enregistrés (défaut txt)
+
+	StatePath, EdgesPath, DOTPath string
+	// Resume reprend l'état enregistré au lieu de repartir des graines.
+	Resume bool
+	// Progress est appelé après chaque article (facultatif).
+	Progress func(CrawlPage)
+}
+
+// CrawlItem est un titre en attente.
+type CrawlItem struct {
+	Title string `json:"title"`
+	Depth int    `json:"depth"`
+}
+
+// CrawlPage est un titre traité : enregistré (Path) ou en échec (Error).
+type CrawlPage struct {
+	Title string `json:"title"`
+	Depth int    `json:"depth"`
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Edge est un lien d'un article visité vers un autre article.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CrawlState est le fichier de reprise, réécrit après chaque vague.
+// Les titres sont qualifiés par leur langue (« fr:Go »).
+type CrawlState struct {
+	Seeds   []string             `json:"seeds"`
+	Fetched int                  `json:"fetched"`
+	Queue   []CrawlItem          `json:"queue"`
+	Pages   map[string]CrawlPage `json:"pages"`
+	Edges   []Edge               `json:"edges"`
+}
+
+// CrawlReport résume un crawl.
+type CrawlReport struct {
+	Saved     int    `json:"saved"`
+	Failed    int    `json:"failed"`
+	Pending   int    `json:"pending"`
+	Edges     int    `json:"edges"`
+	StatePath string `json:"state_path"`
+	EdgesPath string `json:"edges_path"`
+	DOTPath   string `json:"dot_path"`
+}
+
+// Crawl part des graines et suit les liens internes en largeur, vague par
+// vague (une profondeur à la fois, via FetchMany), jusqu'à MaxDepth et
+// MaxPages. Chaque article est enregistré avec SaveAs ; l'état est écrit
+// après chaque vague pour reprendre un crawl interrompu (ctx annulé).
+func (c *Client) Crawl(ctx context.Context, opt CrawlOptions) (CrawlReport, error) {
+	if opt.StatePath == "" {
+		opt.StatePath = filepath.Join(opt.OutDir, "crawl_state.json")
+	}
+	if opt.EdgesPath == "" {
+		opt.EdgesPath = filepath.Join(opt.OutDir, "crawl_edges.tsv")
+	}
+	if opt.DOTPath == "" {
+		opt.DOTPath = filepath.Join(opt.OutDir, "crawl.dot")
+	}
+	if len(opt.Namespaces) == 0 {
+		opt.Namespaces = []int{0}
+	}
+	rep := CrawlReport{StatePath: opt.StatePath, EdgesPath: opt.EdgesPath, DOTPath: opt.DOTPath}
+
+	st, err := c.initState(opt)
+	if err != nil {
+		return rep, err
+	}
+	seen := map[string]bool{}
+	for k := range st.Pages {
+		seen[k] = true
+	}
+	for _, it := range st.Queue {
+		seen[it.Title] = true
+	}
+
+	for len(st.Queue) > 0 && st.Fetched < opt.MaxPages && ctx.Err() == nil {
+		// vague : titres de même profondeur, dans la limite du budget
+		depth := st.Queue[0].Depth
+		n := 0
+		for n < len(st.Queue) && st.Queue[n].Depth == depth && st.Fetched+n < opt.MaxPages {
+			n++
+		}
+		wave := st.Queue[:n]
+		rest := slices.Clone(st.Queue[n:])
+		titles := make([]string, n)
+		for i, it := range wave {
+			titles[i] = it.Title
+		}
+
+		res := c.FetchMany(ctx, titles)
+		var retry, found []CrawlItem
+		for _, f := range res.Failed {
+			if ctx.Err() != nil && errors.Is(f.Err, ctx.Err()) {
+				// interrompu : reste dans la file
+				retry = append(retry, CrawlItem{Title: f.Title, Depth: depth})
+				continue
+			}
+			p := CrawlPage{Title: f.Title, Depth: depth, Error: f.Err.Error()}
+			st.Pages[f.Title] = p
+			st.Fetched++
+			c.progress(opt, p)
+		}
+		for _, a := range res.Articles {
+			key := a.Lang + ":" + a.Title
+			var links []Link
+			var lerr error
+			if depth < opt.MaxDepth {
+				links, lerr = c.Links(ctx, a)
+				if ctx.Err() != nil {
+					// liens incomplets : l'article sera repris
+					asked := key
+					if a.Requested != "" {
+						asked = a.Lang + ":" + a.Requested
+					}
+					retry = append(retry, CrawlItem{Title: asked, Depth: depth})
+					continue
+				}
+			}
+
+			p := CrawlPage{Title: key, Depth: depth}
+			if p.Path, err = SaveAs(a, opt.OutDir, opt.Format); err != nil {
+				return rep, err
+			}
+			if lerr != nil {
+				p.Error = "liens : " + lerr.Error()
+			}
+			st.Fetched++
+			st.Pages[key] = p
+			seen[key] = true
+			if a.Requested != "" {
+				// redirection : le titre demandé pointe vers la même page
+				st.Pages[a.Lang+":"+a.Requested] = p
+			}
+			for _, l := range links {
+				if !slices.Contains(opt.Namespaces, l.NS) {
+					continue
+				}
+				to := a.Lang + ":" + l.Title
+				st.Edges = append(st.Edges, Edge{From: key, To: to})
+				if !seen[to] {
+					seen[to] = true
+					found = append(found, CrawlItem{Title: to, Depth: depth + 1})
+				}
+			}
+			c.progress(opt, p)
+		}
+
+		st.Queue = append(append(retry, rest...), found...)
+		if err := writeState(opt.StatePath, st); err != nil {
+			return rep, err
+		}
+	}
+
+	for k, p := range st.Pages {
+		// les alias de redirection ne comptent pas
+		if k != p.Title {
+			continue
+		}
+		if p.Path != "" {
+			rep.Saved++
+		} else {
+			rep.Failed++
+		}
+	}
+	rep.Pending, rep.Edges = len(st.Queue), len(st.Edges)
+	if err := writeEdges(opt.EdgesPath, st); err != nil {
+		return rep, err
+	}
+	if err := writeDOT(opt.DOTPath, st); err != nil {
+		return rep, err
+	}
+	return rep, ctx.Err()
+}
+
+func (c *Client) progress(opt CrawlOptions, p CrawlPage) {
+	if opt.Progress != nil {
+		opt.Progress(p)
+	}
+}
+
+// initState lit l'état à reprendre ou le crée à partir des graines.
+func (c *Client) initState(opt CrawlOptions) (*CrawlState, error) {
+	if opt.Resume {
+		b, err := os.ReadFile(opt.StatePath)
+		if err != nil {
+			return nil, fmt.Errorf("reprise impossible : %w", err)
+		}
+		var st CrawlState
+		if err := json.Unmarshal(b, &st); err != nil {
+			return nil, fmt.Errorf("état de crawl illisible (%s) : %w", opt.StatePath, err)
+		}
+		if st.Pages == nil {
+			st.Pages = map[string]CrawlPage{}
+		}
+		return &st, nil
+	}
+
+	st := &CrawlState{Pages: map[string]CrawlPage{}}
+	seen := map[string]bool{}
+	for _, s := range opt.Seeds {
+		lang, title := SplitTitle(s)
+		if title == "" {
+			continue
+		}
+		if lang == "" {
+			lang = c.Lang
+		}
+		key := lang + ":" + title
+		if !seen[key] {
+			seen[key] = true
+			st.Seeds = append(st.Seeds, key)
+			st.Queue = append(st.Queue, CrawlItem{Title: key})
+		}
+	}
+	if len(st.Queue) == 0 {
+		return nil, errors.New("aucun titre de départ")
+	}
+	return st, nil
+}
+
+func writeState(path string, st *CrawlState) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeAtomic(path, b)
+}
+
+// writeEdges écrit la liste d'arêtes « source<TAB>cible ».
+func writeEdges(path string, st *CrawlState) error {
+	return writeText(path, func(w *bufio.Writer) {
+		for _, e := range st.Edges {
+			fmt.Fprintf(w, "%s\t%s\n", e.From, e.To)
+		}
+	})
+}
+
+// writeDOT écrit le graphe Graphviz ; les articles visités sont colorés.
+func writeDOT(path string, st *CrawlState) error {
+	return writeText(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "digraph wiki {")
+		fmt.Fprintln(w, "  node [shape=box];")
+		keys := make([]string, 0, len(st.Pages))
+		for k, p := range st.Pages {
+			if k == p.Title && p.Path != "" {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s [style=filled, fillcolor=lightblue];\n", strconv.Quote(k))
+		}
+		for _, e := range st.Edges {
+			fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+		fmt.Fprintln(w, "}")
+	})
+}
+
+func writeText(path string, fill func(w *bufio.Writer)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fill(w)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
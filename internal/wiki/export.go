@@ -0,0 +1,242 @@
+package wiki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SaveFormat est le format d'un article enregistré.
+type SaveFormat string
+
+const (
+	FormatText     SaveFormat = "txt"
+	FormatMarkdown SaveFormat = "md"
+	FormatJSON     SaveFormat = "json"
+	FormatHTML     SaveFormat = "html"
+)
+
+// ParseSaveFormat lit un nom de format (markdown et htm acceptés).
+func ParseSaveFormat(s string) (SaveFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "txt", "text":
+		return FormatText, nil
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("format d'article inconnu %q (txt, md, json, html)", s)
+}
+
+// maxSlug borne la longueur du titre dans un nom de fichier.
+const maxSlug = 80
+
+// translit remplace les lettres accentuées courantes par leur base ASCII.
+var translit = map[rune]string{
+	'à': "a", 'â': "a", 'ä': "a", 'á': "a", 'ã': "a", 'å': "a", 'ā': "a",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'ö': "o", 'õ': "o", 'ø': "o", 'ō': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ů': "u",
+	'ý': "y", 'ÿ': "y",
+	'š': "s", 'ś': "s", 'ž': "z", 'ź': "z", 'ż': "z", 'ř': "r", 'ł': "l", 'đ': "d",
+	'æ': "ae", 'œ': "oe", 'ß': "ss",
+}
+
+// Slug transforme un titre en nom de fichier sûr : minuscules ASCII,
+// chiffres et tirets, sans accents, au plus maxSlug caractères.
+func Slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case translit[r] != "":
+			b.WriteString(translit[r])
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	s := strings.Trim(b.String(), "-")
+	if len(s) > maxSlug {
+		s = strings.TrimRight(s[:maxSlug], "-")
+	}
+	if s == "" {
+		s = "article"
+	}
+	return s
+}
+
+// FileName renvoie le nom de fichier d'un article : wiki_<lang>_<slug>.<ext>.
+// Quand le slug perd de l'information (accents, ponctuation, casse hors
+// première lettre, troncature), un suffixe tiré du titre exact évite que
+// deux titres proches donnent le même fichier.
+func FileName(lang, title string, f SaveFormat) string {
+	slug := Slug(title)
+	if slug != lossless(title) {
+		sum := sha256.Sum256([]byte(lang + ":" + title))
+		slug += "-" + hex.EncodeToString(sum[:4])
+	}
+	if lang == "" {
+		return fmt.Sprintf("wiki_%s.%s", slug, f)
+	}
+	return fmt.Sprintf("wiki_%s_%s.%s", lang, slug, f)
+}
+
+// lossless est le slug attendu quand la conversion ne perd rien : espaces
+// en tirets, première lettre en minuscule (insensible à la casse sur les
+// wikis). Un titre qui contient déjà « - » ou « _ » n'en a pas : sans
+// suffixe, « Saint-denis » et « Saint denis » partageraient un fichier.
+func lossless(title string) string {
+	if strings.ContainsAny(title, "-_") {
+		return ""
+	}
+	t := strings.ReplaceAll(title, " ", "-")
+	r, n := utf8.DecodeRuneInString(t)
+	return string(unicode.ToLower(r)) + t[n:]
+}
+
+// Save enregistre a au format texte dans outDir.
+func Save(a *Article, outDir string) (string, error) {
+	return SaveAs(a, outDir, FormatText)
+}
+
+// SaveAs enregistre a dans outDir au format f et renvoie le chemin.
+func SaveAs(a *Article, outDir string, f SaveFormat) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch f {
+	case FormatText, "":
+		f = FormatText
+		b = []byte(a.textString())
+	case FormatMarkdown:
+		b = []byte(a.markdownString())
+	case FormatJSON:
+		b, err = json.MarshalIndent(a, "", "  ")
+		b = append(b, '\n')
+	case FormatHTML:
+		b = []byte(a.htmlString())
+	default:
+		err = fmt.Errorf("format d'article inconnu %q", f)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(outDir, FileName(a.Lang, a.Title, f))
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, b, 0o644)
+}
+
+// sectionsAt renvoie les sections qui commencent avant le paragraphe i.
+func (a *Article) sectionsAt(i int) []Section {
+	var out []Section
+	for _, s := range a.Sections {
+		if s.Start == i {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (a *Article) textString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s ===\n", a.Title)
+	fmt.Fprintf(&b, "Source : %s (%s)\n", a.URL, a.Source)
+	if a.RevID != 0 {
+		fmt.Fprintf(&b, "Révision : %d du %s\n", a.RevID, a.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+	if a.Requested != "" {
+		fmt.Fprintf(&b, "Redirigé depuis : %s\n", a.Requested)
+	}
+	if len(a.Categories) > 0 {
+		b.WriteString("Catégories : " + strings.Join(a.Categories, ", ") + "\n")
+	}
+	b.WriteString("\n")
+	for i := 0; i <= len(a.Text); i++ {
+		for _, s := range a.sectionsAt(i) {
+			eq := strings.Repeat("=", s.Level)
+			fmt.Fprintf(&b, "%s %s %s\n\n", eq, s.Title, eq)
+		}
+		if i < len(a.Text) {
+			b.WriteString(a.Text[i] + "\n\n")
+		}
+	}
+	fmt.Fprintf(&b, "--- Stats ---\nMots : %d\nLongueur moyenne : %.2f\n\n", a.Words, a.AvgLen)
+	a.Vocab.WriteText(&b, a.Title)
+	return b.String()
+}
+
+func (a *Article) markdownString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", a.Title)
+	fmt.Fprintf(&b, "- Source : <%s>\n", a.URL)
+	fmt.Fprintf(&b, "- Langue : %s\n", a.Lang)
+	if a.RevID != 0 {
+		fmt.Fprintf(&b, "- Révision : %d (%s)\n", a.RevID, a.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "- Téléchargé : %s\n", a.Fetched.Format("2006-01-02 15:04:05"))
+	if a.Requested != "" {
+		fmt.Fprintf(&b, "- Redirigé depuis : %s\n", a.Requested)
+	}
+	if len(a.Categories) > 0 {
+		fmt.Fprintf(&b, "- Catégories : %s\n", strings.Join(a.Categories, ", "))
+	}
+	fmt.Fprintf(&b, "- Mots : %d (longueur moyenne %.2f)\n\n", a.Words, a.AvgLen)
+	for i := 0; i <= len(a.Text); i++ {
+		for _, s := range a.sectionsAt(i) {
+			fmt.Fprintf(&b, "%s %s\n\n", strings.Repeat("#", min(s.Level, 6)), s.Title)
+		}
+		if i < len(a.Text) {
+			b.WriteString(a.Text[i] + "\n\n")
+		}
+	}
+	return b.String()
+}
+
+func (a *Article) htmlString() string {
+	var b strings.Builder
+	e := html.EscapeString
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"%s\">\n<head>\n<meta charset=\"utf-8\">\n", e(a.Lang))
+	fmt.Fprintf(&b, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n<dl>\n", e(a.Title), e(a.Title))
+	fmt.Fprintf(&b, "<dt>Source</dt><dd><a href=\"%s\">%s</a></dd>\n", e(a.URL), e(a.URL))
+	if a.RevID != 0 {
+		fmt.Fprintf(&b, "<dt>Révision</dt><dd>%d (%s)</dd>\n", a.RevID, a.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "<dt>Téléchargé</dt><dd>%s</dd>\n", a.Fetched.Format("2006-01-02 15:04:05"))
+	if len(a.Categories) > 0 {
+		fmt.Fprintf(&b, "<dt>Catégories</dt><dd>%s</dd>\n", e(strings.Join(a.Categories, ", ")))
+	}
+	fmt.Fprintf(&b, "<dt>Mots</dt><dd>%d (longueur moyenne %.2f)</dd>\n</dl>\n", a.Words, a.AvgLen)
+	for i := 0; i <= len(a.Text); i++ {
+		for _, s := range a.sectionsAt(i) {
+			lvl := min(max(s.Level, 2), 6)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", lvl, e(s.Title), lvl)
+		}
+		if i < len(a.Text) {
+			fmt.Fprintf(&b, "<p>%s</p>\n", e(a.Text[i]))
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Valeurs par défaut des clients créés par NewClient.
+const (
+	DefaultConcurrency = 4
+	DefaultRate        = 5 // requêtes par seconde
+	DefaultRetries     = 3
+	DefaultBackoff     = 500 * time.Millisecond
+
+	// maxDelay borne l'attente entre deux essais, Retry-After compris.
+	maxDelay = time.Minute
+)
+
+// Limiter est un seau à jetons : Rate requêtes par seconde en régime
+// établi, jusqu'à Burst d'affilée. Un Limiter nil ne limite rien.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter crée un seau plein ; rate <= 0 renvoie nil (pas de limite).
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &Limiter{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// Wait prend un jeton, en attendant si le seau est vide.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// le jeton est réservé tout de suite : les suivants attendent plus
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// do envoie req en respectant la limite de débit. Les erreurs réseau,
+// 429 et 5xx sont retentées jusqu'à Retries fois, après Retry-After ou
+// un délai exponentiel.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTP.Do(req.WithContext(ctx))
+		if attempt >= c.Retries || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(c.Backoff, attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(d, maxDelay)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff renvoie base·2^attempt, plus une part aléatoire jusqu'à 50 %.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = DefaultBackoff
+	}
+	d := base << min(attempt, 10)
+	d += time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return min(d, maxDelay)
+}
+
+// retryAfter lit un en-tête Retry-After (secondes ou date HTTP).
+func retryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// FetchError est l'échec d'un titre de FetchMany.
+type FetchError struct {
+	Title string
+	Err   error
+}
+
+func (e *FetchError) Error() string { return e.Title + " : " + e.Err.Error() }
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// Result regroupe les articles obtenus et les titres en échec, dans
+// l'ordre de la demande.
+type Result struct {
+	Articles []*Article
+	Failed   []*FetchError
+}
+
+// Err renvoie nil si tous les titres ont été obtenus.
+func (r Result) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	errs := make([]error, len(r.Failed))
+	for i, f := range r.Failed {
+		errs[i] = f
+	}
+	return fmt.Errorf("%d article(s) en échec : %w", len(r.Failed), errors.Join(errs...))
+}
+
+// FetchMany télécharge plusieurs articles de Wikipédia en français.
+func FetchMany(titles []string) Result {
+	return NewClient("fr", "").FetchMany(context.Background(), titles)
+}
+
+// FetchMany télécharge titles avec Concurrency requêtes simultanées au
+// plus. Un échec n'empêche pas les autres titres ; l'annulation de ctx
+// arrête les téléchargements en cours et à venir.
+func (c *Client) FetchMany(ctx context.Context, titles []string) Result {
+	var todo []string
+	for _, t := range titles {
+		if t = strings.TrimSpace(t); t != "" {
+			todo = append(todo, t)
+		}
+	}
+
+	arts := make([]*Article, len(todo))
+	errs := make([]error, len(todo))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(c.Concurrency, 1), len(todo)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				arts[i], errs[i] = c.FetchContext(ctx, todo[i])
+			}
+		}()
+	}
+	for i := range todo {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var r Result
+	for i, t := range todo {
+		if errs[i] != nil {
+			r.Failed = append(r.Failed, &FetchError{Title: t, Err: errs[i]})
+			continue
+		}
+		r.Articles = append(r.Articles, arts[i])
+	}
+	return r
+}
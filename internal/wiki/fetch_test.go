@@ -0,0 +1,255 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const apiBody = `{"query":{"pages":[{"pageid":12,"title":"Go (langage)",
+"extract":"Go est un langage.\n\n== Histoire ==\nConçu chez Google.",
+"revisions":[{"revid":345,"timestamp":"2024-05-01T10:00:00Z"}],
+"categories":[{"title":"Catégorie:Langage de programmation"}]}]}}`
+
+// reply est une réponse préparée du serveur de test.
+type reply struct {
+	status     int
+	retryAfter string
+	etag       string
+	body       string
+}
+
+// apiServer répond aux requêtes dans l'ordre de replies (la dernière est
+// répétée) et note l'en-tête If-None-Match de chacune.
+type apiServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	replies []reply
+	inm     []string
+}
+
+func newAPIServer(t *testing.T, replies ...reply) *apiServer {
+	s := &apiServer{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		n := len(s.inm)
+		s.inm = append(s.inm, r.Header.Get("If-None-Match"))
+		rep := s.replies[min(n, len(s.replies)-1)]
+		s.mu.Unlock()
+
+		if rep.retryAfter != "" {
+			w.Header().Set("Retry-After", rep.retryAfter)
+		}
+		if rep.etag != "" {
+			w.Header().Set("ETag", rep.etag)
+		}
+		w.WriteHeader(rep.status)
+		w.Write([]byte(rep.body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *apiServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.inm)
+}
+
+func testClient(s *apiServer) *Client {
+	c := NewClient("fr", s.URL)
+	c.HTTP = s.Client()
+	c.Limiter = nil
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestFetchAPIRetry(t *testing.T) {
+	ok := reply{status: 200, body: apiBody}
+	tests := []struct {
+		name     string
+		retries  int
+		replies  []reply
+		requests int
+		minWait  time.Duration
+		wantErr  bool
+		status   int // code de StatusError attendu
+	}{
+		{"succès direct", 3, []reply{ok}, 1, 0, false, 0},
+		{"503 puis succès", 3, []reply{{status: 503}, {status: 502}, ok}, 3, 0, false, 0},
+		{"429 et Retry-After", 3, []reply{{status: 429, retryAfter: "1"}, ok}, 2, time.Second, false, 0},
+		{"Retry-After invalide", 3, []reply{{status: 429, retryAfter: "bientôt"}, ok}, 2, 0, false, 0},
+		{"essais épuisés", 2, []reply{{status: 500}}, 3, 0, true, 500},
+		{"sans nouvel essai", 0, []reply{{status: 503}, ok}, 1, 0, true, 503},
+		{"404 non retenté", 3, []reply{{status: 404}, ok}, 1, 0, true, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAPIServer(t, tt.replies...)
+			c := testClient(s)
+			c.Retries = tt.retries
+
+			start := time.Now()
+			a, err := c.fetchAPI(context.Background(), "fr", "Go")
+			if n := len(s.requests()); n != tt.requests {
+				t.Errorf("%d requêtes, veut %d", n, tt.requests)
+			}
+			if d := time.Since(start); d < tt.minWait {
+				t.Errorf("attente %v, veut au moins %v", d, tt.minWait)
+			}
+			if tt.wantErr {
+				var se *StatusError
+				if !errors.As(err, &se) || se.Code != tt.status {
+					t.Errorf("err = %v, veut StatusError %d", err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Title != "Go (langage)" || a.Requested != "Go" || a.RevID != 345 || a.PageID != 12 {
+				t.Errorf("article %+v", a)
+			}
+			if len(a.Sections) != 1 || a.Sections[0].Title != "Histoire" {
+				t.Errorf("sections %+v", a.Sections)
+			}
+			if !slices.Equal(a.Categories, []string{"Langage de programmation"}) {
+				t.Errorf("catégories %q", a.Categories)
+			}
+		})
+	}
+}
+
+func TestFetchAPIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"absent", `{"query":{"pages":[{"title":"Go","missing":true}]}}`, ErrNotFound},
+		{"invalide", `{"query":{"pages":[{"title":"Go","invalid":true}]}}`, ErrNotFound},
+		{"erreur API", `{"error":{"code":"badtitle","info":"Bad title"}}`, nil},
+		{"sans page", `{"query":{"pages":[]}}`, nil},
+		{"extrait vide", `{"query":{"pages":[{"title":"Go","extract":" "}]}}`, nil},
+		{"JSON illisible", `<html>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAPIServer(t, reply{status: 200, body: tt.body})
+			_, err := testClient(s).fetchAPI(context.Background(), "fr", "Go")
+			if err == nil {
+				t.Fatal("pas d'erreur")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, veut %v", err, tt.want)
+			}
+			if n := len(s.requests()); n != 1 {
+				t.Errorf("%d requêtes, veut 1", n)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{" 5 ", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"demain", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v ; veut %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+	d, ok := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if !ok || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("date dans une heure : %v, %v", d, ok)
+	}
+}
+
+const siteinfoBody = `{"query":{"namespaces":{
+"0":{"id":0,"name":"","content":true},
+"6":{"id":6,"name":"Datei","canonical":"File"},
+"14":{"id":14,"name":"Kategorie","canonical":"Category"},
+"100":{"id":100,"name":"Portal","canonical":"Portal"}},
+"namespacealiases":[{"id":6,"alias":"Bild"}]}}`
+
+const linksPage = `<div id="mw-content-text">
+<a href="/wiki/Kategorie:Programmiersprache">a</a>
+<a href="/wiki/Category:Go">b</a>
+<a href="/wiki/Bild:Logo.svg">c</a>
+<a href="/wiki/Cat%C3%A9gorie:Langage">d</a>
+<a href="/wiki/Star_Wars:_Episode_I">e</a>
+</div>`
+
+func TestLinksNamespaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		siteinfo reply
+		apiPath  string
+		want     []int // NS des liens de linksPage
+		requests int   // requêtes siteinfo
+	}{
+		{"siteinfo", reply{status: 200, body: siteinfoBody}, DefaultAPIPath, []int{14, 14, 6, 0, 0}, 1},
+		// noms français intégrés, sans « Bild » ni « Kategorie »
+		{"siteinfo en échec", reply{status: 500}, DefaultAPIPath, []int{0, 14, 0, 14, 0}, 1},
+		{"siteinfo illisible", reply{status: 200, body: `{"query":{}}`}, DefaultAPIPath, []int{0, 14, 0, 14, 0}, 1},
+		{"sans API", reply{status: 200, body: siteinfoBody}, "", []int{0, 14, 0, 14, 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			n := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("meta") == "siteinfo" {
+					mu.Lock()
+					n++
+					mu.Unlock()
+					w.WriteHeader(tt.siteinfo.status)
+					w.Write([]byte(tt.siteinfo.body))
+					return
+				}
+				w.Write([]byte(linksPage))
+			}))
+			defer srv.Close()
+			c := NewClient("de", srv.URL)
+			c.HTTP, c.Limiter, c.Retries, c.APIPath = srv.Client(), nil, 0, tt.apiPath
+
+			ctx := context.Background()
+			// deux articles : siteinfo n'est lu qu'une fois par langue
+			for range 2 {
+				a, err := c.fetchHTML(ctx, "de", "Go")
+				if err != nil {
+					t.Fatal(err)
+				}
+				links, err := c.Links(ctx, a)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int
+				for _, l := range links {
+					got = append(got, l.NS)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("NS %v, veut %v (%+v)", got, tt.want, links)
+				}
+			}
+			if n != tt.requests {
+				t.Errorf("%d requêtes siteinfo, veut %d", n, tt.requests)
+			}
+		})
+	}
+}
@@ -1,137 +1,459 @@
 package wiki
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"fileops/internal/textstat"
+
 	"github.com/PuerkitoBio/goquery"
 )
 
+// DefaultBaseURL est l'adresse des Wikipédia ; {lang} est remplacé par la langue.
+const DefaultBaseURL = "https://{lang}.wikipedia.org"
+
+// Client télécharge des articles pour une langue et une adresse données
+// (Wikipédia, miroir MediaWiki local ou serveur de test).
+type Client struct {
+	Lang    string
+	BaseURL string
+	APIPath string // "" = pas d'API, HTML seulement
+	HTTP    *http.Client
+	Cache   *Cache // nil = pas de cache
+
+	Concurrency int           // téléchargements simultanés de FetchMany
+	Limiter     *Limiter      // nil = pas de limite de débit
+	Retries     int           // nouveaux essais sur 429, 5xx et erreur réseau
+	Backoff     time.Duration // premier délai entre deux essais
+
+	nsMu sync.Mutex
+	ns   map[string]map[string]int // espaces de noms par langue, voir namespaces
+}
+
+// httpClient est partagé par tous les clients (connexions réutilisées).
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewClient crée un client ; baseURL vide vaut DefaultBaseURL.
+func NewClient(lang, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		Lang:    lang,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIPath: DefaultAPIPath,
+		HTTP:    httpClient,
+
+		Concurrency: DefaultConcurrency,
+		Limiter:     NewLimiter(DefaultRate, DefaultRate),
+		Retries:     DefaultRetries,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+var langRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]+)*$`)
+
+// ValidLang indique si lang ressemble à un code de langue Wikipédia.
+func ValidLang(lang string) bool {
+	return langRe.MatchString(lang)
+}
+
+// SplitTitle sépare « en:Title » en langue et titre ; sans préfixe de
+// langue (ou avec un espace de noms comme « Catégorie:X »), lang est vide.
+func SplitTitle(s string) (lang, title string) {
+	s = strings.TrimSpace(s)
+	if p, t, ok := strings.Cut(s, ":"); ok && ValidLang(p) && strings.TrimSpace(t) != "" {
+		return p, strings.TrimSpace(t)
+	}
+	return "", s
+}
+
+// PageURL renvoie l'adresse de l'article title dans la langue lang.
+func (c *Client) PageURL(lang, title string) string {
+	base := strings.ReplaceAll(c.BaseURL, "{lang}", lang)
+	return base + "/wiki/" + url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+}
+
+// Article est un article téléchargé ; Title est le titre canonique (après
+// redirection), Requested le titre demandé s'il diffère. Les métadonnées
+// de révision ne sont connues que via l'API (Source "api").
 type Article struct {
-	Title  string
-	Text   []string
-	Words  int
-	AvgLen float64
+	Title      string    `json:"title"`
+	Requested  string    `json:"requested,omitempty"`
+	Lang       string    `json:"lang"`
+	URL        string    `json:"url"`
+	Source     string    `json:"source"` // api ou html
+	Cache      string    `json:"cache,omitempty"`
+	PageID     int       `json:"page_id,omitempty"`
+	RevID      int       `json:"rev_id,omitempty"`
+	Timestamp  time.Time `json:"timestamp,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+	Sections   []Section `json:"sections,omitempty"`
+	Fetched    time.Time `json:"fetched"`
+
+	Text   []string       `json:"text"`
+	Words  int            `json:"words"`
+	AvgLen float64        `json:"avg_len"`
+	Vocab  textstat.Vocab `json:"vocab"`
+
+	links []Link // liens de la page HTML (nil via l'API, voir Links)
+}
+
+// Section est un titre de section ; Start est l'indice dans Text de son
+// premier paragraphe, Level 2 pour « == Titre == », 3 en dessous…
+type Section struct {
+	Title string `json:"title"`
+	Level int    `json:"level"`
+	Start int    `json:"start"`
 }
 
+// Saved décrit un article enregistré par Save.
+type Saved struct {
+	Title  string  `json:"title"`
+	Lang   string  `json:"lang"`
+	Source string  `json:"source"`
+	RevID  int     `json:"rev_id,omitempty"`
+	Cache  string  `json:"cache,omitempty"`
+	Path   string  `json:"path"`
+	Words  int     `json:"words"`
+	AvgLen float64 `json:"avg_len"`
+}
+
+// Fetch télécharge un article de Wikipédia en français.
 func Fetch(title string) (*Article, error) {
-	escaped := url.PathEscape(title)
-	u := fmt.Sprintf("https://fr.wikipedia.org/wiki/%s", escaped)
+	return NewClient("fr", "").Fetch(title)
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+// Fetch télécharge l'article title ; un préfixe « lang: » remplace la
+// langue du client. L'API MediaWiki est essayée d'abord, la page HTML
+// sert de secours.
+func (c *Client) Fetch(title string) (*Article, error) {
+	return c.FetchContext(context.Background(), title)
+}
 
-	req, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return nil, err
+// FetchContext est Fetch, annulable par ctx.
+func (c *Client) FetchContext(ctx context.Context, title string) (*Article, error) {
+	lang, title := SplitTitle(title)
+	if lang == "" {
+		lang = c.Lang
 	}
-	req.Header.Set("User-Agent", "FileOpsBot/1.0 (+https://example.com/contact)")
+	if c.APIPath == "" {
+		return c.fetchHTML(ctx, lang, title)
+	}
+	a, err := c.fetchAPI(ctx, lang, title)
+	if err == nil || errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+		return a, err
+	}
+	a, herr := c.fetchHTML(ctx, lang, title)
+	if errors.Is(err, ErrOffline) && errors.Is(herr, ErrOffline) {
+		return nil, herr
+	}
+	if herr != nil {
+		return nil, fmt.Errorf("%s:%s : API : %v ; HTML : %w", lang, title, err, herr)
+	}
+	return a, nil
+}
 
-	resp, err := client.Do(req)
+// fetchHTML garde les paragraphes <p> de la page rendue.
+func (c *Client) fetchHTML(ctx context.Context, lang, title string) (*Article, error) {
+	u := c.PageURL(lang, title)
+	body, info, err := c.fetchBody(ctx, lang, title, "html", u)
+	var se *StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("%s:%s : %w", lang, title, ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d pour %s", resp.StatusCode, u)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	var paras []string
-	doc.Find("p").Each(func(_ int, sel *goquery.Selection) {
-		if txt := strings.TrimSpace(sel.Text()); txt != "" {
-			paras = append(paras, txt)
+	var (
+		paras    []string
+		sections []Section
+	)
+	content := doc.Find("#mw-content-text")
+	if content.Length() == 0 {
+		content = doc.Selection
+	}
+	content.Find(".mw-editsection").Remove()
+	content.Find("p, h2, h3, h4").Each(func(_ int, sel *goquery.Selection) {
+		txt := strings.TrimSpace(sel.Text())
+		if txt == "" {
+			return
+		}
+		if name := goquery.NodeName(sel); name != "p" {
+			sections = append(sections, Section{Title: txt, Level: int(name[1] - '0'), Start: len(paras)})
+			return
 		}
+		paras = append(paras, txt)
 	})
 
-	words, avg := statsWords(paras)
+	a := newArticle(title, lang, u, paras)
+	a.Sections = sections
+	a.Source, a.Cache, a.Fetched = "html", info.Cache, info.Fetched
+	a.links = htmlLinks(doc)
+	return a, nil
+}
 
-	return &Article{
-		Title:  title,
-		Text:   paras,
-		Words:  words,
-		AvgLen: avg,
-	}, nil
+// htmlLinks relève les liens /wiki/ du contenu de la page ; leur espace
+// de noms est déduit du titre par Links.
+func htmlLinks(doc *goquery.Document) []Link {
+	sel := doc.Find("#mw-content-text")
+	if sel.Length() == 0 {
+		sel = doc.Selection
+	}
+	links := []Link{}
+	seen := map[string]bool{}
+	sel.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		_, rest, ok := strings.Cut(href, "/wiki/")
+		if !ok || strings.Contains(href, "://") {
+			return
+		}
+		rest, _, _ = strings.Cut(rest, "#")
+		t, err := url.PathUnescape(rest)
+		if err != nil || t == "" {
+			return
+		}
+		t = strings.ReplaceAll(t, "_", " ")
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		links = append(links, Link{Title: t})
+	})
+	return links
 }
 
-func Save(a *Article, outDir string) (string, error) {
-	name := fmt.Sprintf("wiki_%s.txt", a.Title)
-	path := filepath.Join(outDir, name)
+// defaultNamespaces associe les préfixes d'espaces de noms courants (noms
+// canoniques et noms français, en minuscules) à leur numéro MediaWiki ;
+// il sert quand le wiki n'a pas d'API ou que siteinfo échoue.
+var defaultNamespaces = map[string]int{
+	"media": -2, "média": -2, "special": -1, "spécial": -1,
+	"talk": 1, "discussion": 1, "user": 2, "utilisateur": 2,
+	"user talk": 3, "discussion utilisateur": 3,
+	"project": 4, "wikipedia": 4, "wikipédia": 4,
+	"project talk": 5, "wikipedia talk": 5, "discussion wikipédia": 5,
+	"file": 6, "image": 6, "fichier": 6, "file talk": 7, "discussion fichier": 7,
+	"mediawiki": 8, "mediawiki talk": 9, "discussion mediawiki": 9,
+	"template": 10, "modèle": 10, "template talk": 11, "discussion modèle": 11,
+	"help": 12, "aide": 12, "help talk": 13, "discussion aide": 13,
+	"category": 14, "catégorie": 14, "category talk": 15, "discussion catégorie": 15,
+	"portal": 100, "portail": 100, "portal talk": 101, "discussion portail": 101,
+	"projet": 102, "discussion projet": 103,
+	"draft": 118, "draft talk": 119, "module": 828, "module talk": 829, "discussion module": 829,
+}
 
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("=== %s ===\n\n", a.Title))
-	for _, p := range a.Text {
-		b.WriteString(p + "\n\n")
-	}
-	b.WriteString(fmt.Sprintf("--- Stats ---\nMots : %d\nLongueur moyenne : %.2f\n",
-		a.Words, a.AvgLen))
+// réponse de meta=siteinfo (formatversion=2)
+type siteinfoResponse struct {
+	Query struct {
+		Namespaces map[string]struct {
+			ID        int    `json:"id"`
+			Name      string `json:"name"`
+			Canonical string `json:"canonical"`
+		} `json:"namespaces"`
+		Aliases []struct {
+			ID    int    `json:"id"`
+			Alias string `json:"alias"`
+		} `json:"namespacealiases"`
+	} `json:"query"`
+}
 
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		return "", err
+// namespaces renvoie les préfixes d'espaces de noms du wiki de lang (noms
+// locaux, canoniques et alias, en minuscules), lus une fois par langue
+// via siteinfo puis gardés par le client.
+func (c *Client) namespaces(ctx context.Context, lang string) map[string]int {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	if ns, ok := c.ns[lang]; ok {
+		return ns
+	}
+	ns, err := c.fetchNamespaces(ctx, lang)
+	if err != nil {
+		if ctx.Err() != nil {
+			return defaultNamespaces
+		}
+		ns = defaultNamespaces
+	}
+	if c.ns == nil {
+		c.ns = make(map[string]map[string]int)
 	}
-	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+	c.ns[lang] = ns
+	return ns
 }
 
-func statsWords(lines []string) (int, float64) {
-	var count, sum int
-	for _, l := range lines {
-		for _, tok := range strings.Fields(l) {
-			if tok != "" && (tok[0] < '0' || tok[0] > '9') {
-				count++
-				sum += len(tok)
-			}
+func (c *Client) fetchNamespaces(ctx context.Context, lang string) (map[string]int, error) {
+	if c.APIPath == "" {
+		return nil, errors.New("pas d'API")
+	}
+	q := url.Values{
+		"action":        {"query"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+		"meta":          {"siteinfo"},
+		"siprop":        {"namespaces|namespacealiases"},
+	}
+	u := strings.ReplaceAll(c.BaseURL, "{lang}", lang) + c.APIPath + "?" + q.Encode()
+	body, _, err := c.fetchBody(ctx, lang, "", "siteinfo", u)
+	if err != nil {
+		return nil, err
+	}
+	var r siteinfoResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("réponse siteinfo illisible : %w", err)
+	}
+	if len(r.Query.Namespaces) == 0 {
+		return nil, errors.New("réponse siteinfo sans espace de noms")
+	}
+	ns := make(map[string]int)
+	add := func(name string, id int) {
+		if name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", " ")); name != "" {
+			ns[name] = id
 		}
 	}
-	if count == 0 {
-		return 0, 0
+	for _, n := range r.Query.Namespaces {
+		add(n.Name, n.ID)
+		add(n.Canonical, n.ID)
 	}
-	return count, float64(sum) / float64(count)
+	for _, a := range r.Query.Aliases {
+		add(a.Alias, a.ID)
+	}
+	return ns, nil
+}
+
+// namespaceOf renvoie l'espace de noms d'un titre lu dans le HTML : le
+// texte avant « : » n'en est un que s'il figure dans ns
+// (« Star Wars : Episode I » reste un article).
+func namespaceOf(title string, ns map[string]int) int {
+	prefix, _, ok := strings.Cut(title, ":")
+	if !ok {
+		return 0
+	}
+	if id, ok := ns[strings.ToLower(strings.TrimSpace(prefix))]; ok {
+		return id
+	}
+	return 0
+}
+
+// StatusError est une réponse HTTP autre que 200.
+type StatusError struct {
+	Code int
+	URL  string
 }
 
-// FetchMany télécharge plusieurs articles en parallèle
-func FetchMany(titles []string) ([]*Article, error) {
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d pour %s", e.Code, e.URL)
+}
+
+// bodyInfo décrit l'origine d'un corps renvoyé par fetchBody.
+type bodyInfo struct {
+	Cache   string    // état du cache ("" sans cache)
+	Fetched time.Time // téléchargement (celui de la copie servie du cache)
+}
+
+// fetchBody télécharge u ou le sert du cache. kind sépare les réponses
+// API et HTML d'un même titre.
+func (c *Client) fetchBody(ctx context.Context, lang, title, kind, u string) (body []byte, info bodyInfo, err error) {
 	var (
-		wg   sync.WaitGroup
-		resC = make(chan *Article, len(titles))
-		errC = make(chan error, len(titles))
+		m      cacheMeta
+		cached []byte
+		ok     bool
 	)
+	if c.Cache != nil {
+		defer func() { c.Cache.count(info.Cache) }()
+		m, cached, ok = c.Cache.load(lang, title, kind)
+		// entrée d'un autre miroir : inutilisable
+		ok = ok && m.URL == u
+		switch {
+		case ok && (c.Cache.Offline || m.fresh(c.Cache.MaxAge)):
+			return cached, bodyInfo{CacheHit, m.Fetched}, nil
+		case c.Cache.Offline:
+			return nil, info, fmt.Errorf("%s:%s : %w", lang, title, ErrOffline)
+		}
+	}
 
-	for _, t := range titles {
-		title := strings.TrimSpace(t)
-		if title == "" {
-			continue
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, info, err
+	}
+	req.Header.Set("User-Agent", "FileOpsBot/1.0 (+https://example.com/contact)")
+	if ok {
+		if m.ETag != "" {
+			req.Header.Set("If-None-Match", m.ETag)
+		}
+		if m.LastModified != "" {
+			req.Header.Set("If-Modified-Since", m.LastModified)
+		}
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		if ok && ctx.Err() == nil {
+			return cached, bodyInfo{CacheStale, m.Fetched}, nil
 		}
-		wg.Add(1)
-		go func(tt string) {
-			defer wg.Done()
-			a, err := Fetch(tt)
-			if err != nil {
-				errC <- err
-				return
-			}
-			resC <- a
-		}(title)
-	}
-	wg.Wait()
-	close(resC)
-	close(errC)
-
-	if len(errC) > 0 {
-		return nil, <-errC
-	}
-	var arts []*Article
-	for a := range resC {
-		arts = append(arts, a)
-	}
-	return arts, nil
+		return nil, info, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		m.Fetched = time.Now()
+		if err := c.Cache.store(lang, title, kind, m, nil); err != nil {
+			return nil, info, fmt.Errorf("cache : %w", err)
+		}
+		return cached, bodyInfo{CacheRevalidated, m.Fetched}, nil
+	case resp.StatusCode >= 500 && ok:
+		return cached, bodyInfo{CacheStale, m.Fetched}, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, info, &StatusError{Code: resp.StatusCode, URL: u}
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, info, err
+	}
+	if c.Cache == nil {
+		return body, bodyInfo{Fetched: time.Now()}, nil
+	}
+	m = cacheMeta{
+		URL:          u,
+		Lang:         lang,
+		Title:        title,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}
+	if err := c.Cache.store(lang, title, kind, m, body); err != nil {
+		return nil, info, fmt.Errorf("cache : %w", err)
+	}
+	return body, bodyInfo{CacheMiss, m.Fetched}, nil
+}
+
+// newArticle calcule les statistiques des paragraphes.
+func newArticle(title, lang, u string, paras []string) *Article {
+	counter := textstat.Count(paras)
+	words, avg := counter.Words()
+	return &Article{
+		Title:  title,
+		Lang:   lang,
+		URL:    u,
+		Text:   paras,
+		Words:  words,
+		AvgLen: avg,
+		Vocab:  counter.Vocab(textstat.DefaultTopN),
+	}
 }